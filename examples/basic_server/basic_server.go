@@ -37,15 +37,13 @@ func main() {
 					fmt.Println("Unknow packet type!")
 
 				case *osc.Message:
-					fmt.Printf("-- OSC Message: ")
-					osc.PrintMessage(packet.(*osc.Message))
+					fmt.Println("-- OSC Message:", packet)
 
 				case *osc.Bundle:
 					fmt.Println("-- OSC Bundle:")
 					bundle := packet.(*osc.Bundle)
-					for i, message := range bundle.Messages {
-						fmt.Printf("  -- OSC Message #%d: ", i+1)
-						osc.PrintMessage(message)
+					for i, element := range bundle.Elements {
+						fmt.Printf("  -- OSC Element #%d: %v\n", i+1, element)
 					}
 				}
 			}
@@ -1,13 +1,17 @@
 package main
 
-import "github.com/chabad360/go-osc/osc"
+import (
+	"fmt"
+
+	"github.com/chabad360/go-osc/osc"
+)
 
 func main() {
 	addr := "127.0.0.1:8765"
 
 	d := osc.NewStandardDispatcher()
 	d.AddMsgHandler("/message/address", func(msg *osc.Message) {
-		osc.PrintMessage(msg)
+		fmt.Println(msg)
 	})
 	server := &osc.Server{
 		Addr:       addr,
@@ -0,0 +1,181 @@
+package osc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+////
+// Typed argument access
+////
+
+// Scan copies the arguments of the message into the values pointed at by
+// dest, similar to sql.Rows.Scan. The arguments are stored in order; dest may
+// be shorter than the argument list, the remaining arguments are ignored.
+//
+// Each destination must be a pointer to a type that GetTypeTag understands or
+// to an interface{}. In addition to exact matches, the following conversions
+// are performed:
+//
+//	int32   -> int64
+//	float32 -> float64
+//	Symbol  -> string
+//
+// The 'T' and 'F' arguments are stored in *bool destinations. A nil pointer
+// in dest skips the corresponding argument.
+func (m *Message) Scan(dest ...interface{}) error {
+	if len(dest) > len(m.Arguments) {
+		return fmt.Errorf("Scan: expected %d destination arguments, not %d", len(m.Arguments), len(dest))
+	}
+
+	for i, d := range dest {
+		if d == nil {
+			continue
+		}
+		v := reflect.ValueOf(d)
+		if v.Kind() != reflect.Ptr {
+			return fmt.Errorf("Scan: destination %d is not a pointer: %T", i, d)
+		}
+		if v.IsNil() {
+			continue
+		}
+		if err := setArg(v.Elem(), m.Arguments[i]); err != nil {
+			return fmt.Errorf("Scan: argument %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Int32 returns the argument at index `i` as an int32.
+func (m *Message) Int32(i int) (int32, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := arg.(int32)
+	if !ok {
+		return 0, argTypeError(i, arg, "int32")
+	}
+	return v, nil
+}
+
+// Int64 returns the argument at index `i` as an int64. An int32 argument is
+// widened to an int64.
+func (m *Message) Int64(i int) (int64, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return 0, err
+	}
+	switch v := arg.(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	default:
+		return 0, argTypeError(i, arg, "int64")
+	}
+}
+
+// Float32 returns the argument at index `i` as a float32.
+func (m *Message) Float32(i int) (float32, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := arg.(float32)
+	if !ok {
+		return 0, argTypeError(i, arg, "float32")
+	}
+	return v, nil
+}
+
+// Float64 returns the argument at index `i` as a float64. A float32 argument
+// is widened to a float64.
+func (m *Message) Float64(i int) (float64, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return 0, err
+	}
+	switch v := arg.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	default:
+		return 0, argTypeError(i, arg, "float64")
+	}
+}
+
+// StringArg returns the argument at index `i` as a string. A Symbol argument
+// is converted to a string. (String is taken by the fmt.Stringer interface.)
+func (m *Message) StringArg(i int) (string, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return "", err
+	}
+	switch v := arg.(type) {
+	case string:
+		return v, nil
+	case Symbol:
+		return string(v), nil
+	default:
+		return "", argTypeError(i, arg, "string")
+	}
+}
+
+// Bool returns the 'T' or 'F' argument at index `i` as a bool.
+func (m *Message) Bool(i int) (bool, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return false, err
+	}
+	v, ok := arg.(bool)
+	if !ok {
+		return false, argTypeError(i, arg, "bool")
+	}
+	return v, nil
+}
+
+// Blob returns the argument at index `i` as a blob.
+func (m *Message) Blob(i int) ([]byte, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := arg.([]byte)
+	if !ok {
+		return nil, argTypeError(i, arg, "[]byte")
+	}
+	return v, nil
+}
+
+// Timetag returns the argument at index `i` as a Timetag.
+func (m *Message) Timetag(i int) (Timetag, error) {
+	arg, err := m.argument(i)
+	if err != nil {
+		return 0, err
+	}
+	v, ok := arg.(Timetag)
+	if !ok {
+		return 0, argTypeError(i, arg, "Timetag")
+	}
+	return v, nil
+}
+
+// argument returns the argument at index `i`.
+func (m *Message) argument(i int) (interface{}, error) {
+	if i < 0 || i >= len(m.Arguments) {
+		return nil, fmt.Errorf("argument index out of range: %d", i)
+	}
+	return m.Arguments[i], nil
+}
+
+// argTypeError returns the error for an argument `arg` at index `i` that
+// can't be returned as `want`.
+func argTypeError(i int, arg interface{}, want string) error {
+	if arg == nil {
+		return fmt.Errorf("argument %d is Nil, not %s", i, want)
+	}
+	return fmt.Errorf("argument %d is %T, not %s", i, arg, want)
+}
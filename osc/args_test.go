@@ -0,0 +1,105 @@
+package osc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMessage_Scan(t *testing.T) {
+	msg := NewMessage("/a", int32(1), float32(2), "three", true, Symbol("five"), nil, []byte{7})
+
+	var (
+		i   int32
+		f   float64
+		s   string
+		b   bool
+		sym string
+		n   interface{} = "not nil"
+		blb []byte
+	)
+	if err := msg.Scan(&i, &f, &s, &b, &sym, &n, &blb); err != nil {
+		t.Fatalf("Scan() unexpected error: %s", err)
+	}
+	if i != 1 || f != 2 || s != "three" || !b || sym != "five" || n != nil || !reflect.DeepEqual(blb, []byte{7}) {
+		t.Errorf("Scan() = %v %v %v %v %v %v %v", i, f, s, b, sym, n, blb)
+	}
+
+	var l int64
+	if err := msg.Scan(&l, nil); err != nil {
+		t.Errorf("Scan() with fewer destinations unexpected error: %s", err)
+	}
+	if l != 1 {
+		t.Errorf("Scan() widened int32 = %d, want = 1", l)
+	}
+
+	for _, tt := range []struct {
+		desc string
+		dest []interface{}
+	}{
+		{"too_many", []interface{}{&i, &f, &s, &b, &sym, &n, &blb, &i}},
+		{"mismatch", []interface{}{&s}},
+		{"not_pointer", []interface{}{i}},
+		{"narrowing", []interface{}{nil, new(float32), new(int32)}},
+		{"nil_into_string", []interface{}{nil, nil, nil, nil, nil, &s}},
+	} {
+		if err := msg.Scan(tt.dest...); err == nil {
+			t.Errorf("%s: Scan() expected an error", tt.desc)
+		}
+	}
+}
+
+func TestMessage_TypedAccessors(t *testing.T) {
+	msg := NewMessage("/a", int32(1), float32(2), "three", true, Symbol("five"), nil, []byte{7}, Timetag(8), int64(9), float64(10))
+
+	check := func(desc string, got, want interface{}, err error) {
+		t.Helper()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", desc, err)
+			return
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v, want = %v", desc, got, want)
+		}
+	}
+
+	i32, err := msg.Int32(0)
+	check("Int32(0)", i32, int32(1), err)
+	i64, err := msg.Int64(0)
+	check("Int64(0)", i64, int64(1), err)
+	i64, err = msg.Int64(8)
+	check("Int64(8)", i64, int64(9), err)
+	f32, err := msg.Float32(1)
+	check("Float32(1)", f32, float32(2), err)
+	f64, err := msg.Float64(1)
+	check("Float64(1)", f64, float64(2), err)
+	f64, err = msg.Float64(9)
+	check("Float64(9)", f64, float64(10), err)
+	s, err := msg.StringArg(2)
+	check("StringArg(2)", s, "three", err)
+	s, err = msg.StringArg(4)
+	check("StringArg(4)", s, "five", err)
+	b, err := msg.Bool(3)
+	check("Bool(3)", b, true, err)
+	blb, err := msg.Blob(6)
+	check("Blob(6)", blb, []byte{7}, err)
+	tt, err := msg.Timetag(7)
+	check("Timetag(7)", tt, Timetag(8), err)
+
+	for desc, err := range map[string]error{
+		"Int32(1)":     func() error { _, err := msg.Int32(1); return err }(),
+		"Int32(-1)":    func() error { _, err := msg.Int32(-1); return err }(),
+		"Int32(10)":    func() error { _, err := msg.Int32(10); return err }(),
+		"Int64(1)":     func() error { _, err := msg.Int64(1); return err }(),
+		"Float32(0)":   func() error { _, err := msg.Float32(0); return err }(),
+		"Float32(9)":   func() error { _, err := msg.Float32(9); return err }(),
+		"Float64(0)":   func() error { _, err := msg.Float64(0); return err }(),
+		"StringArg(5)": func() error { _, err := msg.StringArg(5); return err }(),
+		"Bool(0)":      func() error { _, err := msg.Bool(0); return err }(),
+		"Blob(2)":      func() error { _, err := msg.Blob(2); return err }(),
+		"Timetag(8)":   func() error { _, err := msg.Timetag(8); return err }(),
+	} {
+		if err == nil {
+			t.Errorf("%s: expected an error", desc)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package osc
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"time"
 )
@@ -38,6 +39,12 @@ func (b *Bundle) MarshalBinary() (bb []byte, err error) {
 
 // LightMarshalBinary allows you to marshal a bundle into a bytes.Buffer to avoid an allocation.
 func (b *Bundle) LightMarshalBinary(data *bytes.Buffer) error {
+	return b.marshal(data, MaxPacketSize)
+}
+
+// marshal appends the bundle to `data`. Messages of `maxSize` bytes or more
+// are rejected.
+func (b *Bundle) marshal(data *bytes.Buffer, maxSize int) error {
 	writePaddedString("#bundle", data)
 
 	// Add the time tag
@@ -46,19 +53,15 @@ func (b *Bundle) LightMarshalBinary(data *bytes.Buffer) error {
 	data.Write(buf)
 
 	// Process all Bundle elements
-	for _, m := range b.Elements {
-		buf, err := m.MarshalBinary()
-		if err != nil {
+	for _, p := range b.Elements {
+		// Reserve the size of the element, it is filled in afterwards
+		start := data.Len()
+		data.Write(padBytes)
+
+		if err := marshalPacket(p, data, maxSize); err != nil {
 			return err
 		}
-
-		// Write the size of the bundle
-		b := make([]byte, bit32Size)
-		binary.BigEndian.PutUint32(b, uint32(len(buf)))
-		data.Write(b)
-
-		// Append the bundle
-		data.Write(buf)
+		binary.BigEndian.PutUint32(data.Bytes()[start:], uint32(data.Len()-start-bit32Size))
 	}
 
 	return nil
@@ -92,14 +95,25 @@ func (b *Bundle) Append(pck Packet) error {
 	return nil
 }
 
-// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// enforcing the DefaultLimits. A *DecodeError is returned if data isn't a
+// valid OSC bundle.
 func (b *Bundle) UnmarshalBinary(data []byte) error {
-	if (len(data) % bit32Size) != 0 {
-		return fmt.Errorf("UnmarshalBinary: data isn't mod 4")
+	return b.unmarshalBinary(data, &DefaultLimits, 1)
+}
+
+// unmarshalBinary parses a bundle with the nesting depth `depth`.
+func (b *Bundle) unmarshalBinary(data []byte, limits *Limits, depth int) error {
+	if exceeds(depth, limits.MaxBundleDepth) {
+		return &DecodeError{Err: ErrLimitExceeded}
 	}
 
-	if len(data) < 20 {
-		return fmt.Errorf("UnmarshalBinary: bundle is too short")
+	if n := len(data) % bit32Size; n != 0 {
+		return &DecodeError{Offset: len(data) - n, Err: ErrBadPadding}
+	}
+
+	if len(data) < len(bundleTagString)+1+bit64Size {
+		return &DecodeError{Err: ErrTruncated}
 	}
 
 	reader := bufPool.Get().(*bytes.Buffer)
@@ -110,31 +124,39 @@ func (b *Bundle) UnmarshalBinary(data []byte) error {
 
 	// Read the '#bundle' OSC string
 	startTag, _, err := readPaddedString(reader)
-	if err != nil {
-		return err
-	}
-
-	if startTag != bundleTagString {
-		return fmt.Errorf("invalid bundle start tag: %s", startTag)
+	if err != nil || startTag != bundleTagString {
+		return &DecodeError{Err: ErrBadBundle}
 	}
 
 	// Read the timetag
-	// Create a new bundle
 	b.Timetag = Timetag(binary.BigEndian.Uint64(reader.Next(bit64Size)))
+	b.Elements = nil
 
 	// Read until the end of the buffer
 	for reader.Len() > 0 {
+		off := len(data) - reader.Len()
+		if exceeds(len(b.Elements)+1, limits.MaxBundleElements) {
+			return &DecodeError{Offset: off, Err: ErrLimitExceeded}
+		}
+
 		// Read the size of the bundle element
-		length := int(binary.BigEndian.Uint32(reader.Next(bit32Size)))
+		length := int(int32(binary.BigEndian.Uint32(reader.Next(bit32Size))))
+		if length < 0 || length%bit32Size != 0 {
+			return &DecodeError{Offset: off, Err: ErrBadLength}
+		}
 		if reader.Len() < length {
-			return fmt.Errorf("invalid bundle element length: %d", length)
+			return &DecodeError{Offset: off, Err: ErrTruncated}
 		}
 
-		p, err := ReadPacket(reader.Next(length))
+		p, err := readPacket(reader.Next(length), limits, depth)
 		if err != nil {
+			var de *DecodeError
+			if errors.As(err, &de) {
+				de.Offset += off + bit32Size
+			}
 			return err
 		}
-		b.Append(p)
+		b.Elements = append(b.Elements, p)
 	}
 
 	return nil
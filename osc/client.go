@@ -1,53 +1,128 @@
 package osc
 
 import (
-	"fmt"
+	"errors"
 	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 )
 
 // Client enables you to send OSC packets. It sends OSC messages and bundles to
-// the given IP address and port.
+// the given IP address and port. The socket is kept open between sends, call
+// Close to release it. A Client is safe for concurrent use, but IP and Port
+// must not be changed while packets are sent, use SetAddr for that.
 type Client struct {
 	IP    string
 	Port  int
 	laddr *net.UDPAddr
+
+	mu sync.Mutex
+	// conn is the open socket, or nil. It is connected to the remote
+	// address, so the datagrams of other hosts are never received on it. It
+	// is opened again if laddr or the remote address change.
+	conn      *net.UDPConn
+	connLaddr *net.UDPAddr
+	connIP    string
+	connPort  int
 }
 
 // NewClient creates a new OSC client. The Client is used to send OSC
 // messages and OSC bundles over an UDP network connection. The `ip` argument
 // specifies the IP address and `port` defines the target port where the
-// messages and bundles will be send to.
+// messages and bundles will be send to. The `ip` argument may also be a host
+// name or an IPv6 address.
 func NewClient(ip string, port int) *Client {
 	return &Client{IP: ip, Port: port, laddr: nil}
 }
 
 // SetLocalAddr sets the local address.
 func (c *Client) SetLocalAddr(ip string, port int) error {
-	laddr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", ip, port))
+	laddr, err := resolveUDPAddr(ip, port)
 	if err != nil {
 		return err
 	}
+	c.mu.Lock()
 	c.laddr = laddr
+	c.mu.Unlock()
 	return nil
 }
 
+// SetAddr sets the IP address and port to which the packets are sent. Unlike
+// setting IP and Port, it may be called while packets are sent.
+func (c *Client) SetAddr(ip string, port int) {
+	c.mu.Lock()
+	c.IP, c.Port = ip, port
+	c.mu.Unlock()
+}
+
 // Send sends an OSC Bundle or an OSC Message.
 func (c *Client) Send(packet Packet) error {
-	addr, err := net.ResolveUDPAddr("udp", fmt.Sprintf("%s:%d", c.IP, c.Port))
+	data, err := packet.MarshalBinary()
 	if err != nil {
 		return err
 	}
-	conn, err := net.DialUDP("udp", c.laddr, addr)
-	if err != nil {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err = c.open(); err != nil {
 		return err
 	}
-	defer conn.Close()
+	_, err = c.conn.Write(data)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		// The error stems from an earlier packet to a port on which nobody
+		// listens. It doesn't concern this packet, which isn't sent, so
+		// send it again and don't report that nobody listens.
+		if _, err = c.conn.Write(data); errors.Is(err, syscall.ECONNREFUSED) {
+			err = nil
+		}
+	}
+	return err
+}
 
-	data, err := packet.MarshalBinary()
+// open opens the socket if there is none, or if the local or remote address
+// changed since it was opened. c.mu must be held.
+func (c *Client) open() error {
+	if c.conn != nil && c.connLaddr == c.laddr && c.connIP == c.IP && c.connPort == c.Port {
+		return nil
+	}
+	c.closeConn()
+
+	raddr, err := resolveUDPAddr(c.IP, c.Port)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialUDP("udp", c.laddr, raddr)
 	if err != nil {
 		return err
 	}
+	c.conn, c.connLaddr, c.connIP, c.connPort = conn, c.laddr, c.IP, c.Port
+	return nil
+}
 
-	_, err = conn.Write(data)
+// closeConn closes the socket if there is one. c.mu must be held.
+func (c *Client) closeConn() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
 	return err
 }
+
+// Close closes the socket of the Client. The Client can still be used
+// afterwards, the next Send opens a new socket.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeConn()
+}
+
+// resolveUDPAddr resolves the UDP address of `host` and `port`. The host may
+// be an IPv6 address with or without brackets.
+func resolveUDPAddr(host string, port int) (*net.UDPAddr, error) {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+}
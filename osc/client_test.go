@@ -1,6 +1,10 @@
 package osc
 
-import "testing"
+import (
+	"net"
+	"testing"
+	"time"
+)
 
 func TestClientSetLocalAddr(t *testing.T) {
 	client := NewClient("localhost", 8967)
@@ -13,3 +17,139 @@ func TestClientSetLocalAddr(t *testing.T) {
 		t.Errorf("Expected laddr to be %s but was %s", expectedAddr, client.laddr.String())
 	}
 }
+
+func TestClientReusesConnection(t *testing.T) {
+	for _, network := range []struct{ listen, ip string }{
+		{"127.0.0.1:0", "127.0.0.1"},
+		{"[::1]:0", "::1"},
+		{"[::1]:0", "[::1]"},
+	} {
+		c, err := net.ListenPacket("udp", network.listen)
+		if err != nil {
+			t.Logf("skipping %s: %s", network.listen, err)
+			continue
+		}
+		defer c.Close()
+
+		client := NewClient(network.ip, c.LocalAddr().(*net.UDPAddr).Port)
+		defer client.Close()
+
+		s := &Server{ReadTimeout: 5 * time.Second}
+		var from []string
+		for i := 0; i < 3; i++ {
+			if err = client.Send(NewMessage("/a")); err != nil {
+				t.Fatalf("%s: Send() unexpected error: %s", network.ip, err)
+			}
+			_, addr, err := s.ReceivePacketFrom(c)
+			if err != nil {
+				t.Fatalf("%s: %s", network.ip, err)
+			}
+			from = append(from, addr.String())
+
+			// The connection is opened again after Close.
+			if i == 1 {
+				client.Close()
+			}
+		}
+		if from[0] != from[1] {
+			t.Errorf("%s: packets sent from %s and %s, want the same address", network.ip, from[0], from[1])
+		}
+	}
+}
+
+func TestClientAddressChange(t *testing.T) {
+	s := &Server{ReadTimeout: 5 * time.Second}
+	var conns []net.PacketConn
+	for i := 0; i < 2; i++ {
+		c, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		conns = append(conns, c)
+	}
+
+	client := NewClient("127.0.0.1", conns[0].LocalAddr().(*net.UDPAddr).Port)
+	defer client.Close()
+	for _, c := range conns {
+		client.SetAddr("127.0.0.1", c.LocalAddr().(*net.UDPAddr).Port)
+		if err := client.Send(NewMessage("/a")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ReceivePacket(c); err != nil {
+			t.Fatalf("packet wasn't sent to the new port: %s", err)
+		}
+	}
+}
+
+func TestClientClosedPort(t *testing.T) {
+	// Find a port on which nobody listens.
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := c.LocalAddr().(*net.UDPAddr).Port
+	c.Close()
+
+	client := NewClient("127.0.0.1", port)
+	defer client.Close()
+	var from net.Addr
+	for i := 0; i < 5; i++ {
+		if err := client.Send(NewMessage("/a")); err != nil {
+			t.Errorf("Send() #%d to a closed port unexpected error: %s", i, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		client.mu.Lock()
+		conn := client.conn
+		client.mu.Unlock()
+		if conn == nil {
+			t.Fatalf("Send() #%d closed the socket", i)
+		}
+		if addr := conn.LocalAddr(); from != nil && addr.String() != from.String() {
+			t.Errorf("Send() #%d sent from %s, want the same address as before %s", i, addr, from)
+		} else {
+			from = addr
+		}
+	}
+}
+
+func TestClientIgnoresOtherHosts(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	stranger, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stranger.Close()
+
+	client := NewClient("127.0.0.1", server.LocalAddr().(*net.UDPAddr).Port)
+	defer client.Close()
+	if err = client.Send(NewMessage("/a")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the datagrams of the remote address reach the socket of the
+	// Client, so the ones of other hosts don't pile up in it.
+	client.mu.Lock()
+	conn := client.conn
+	client.mu.Unlock()
+	if _, err = stranger.WriteTo([]byte("hello"), conn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = server.WriteTo([]byte("reply"), conn.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "reply" {
+		t.Errorf("socket received %q, want = %q", got, "reply")
+	}
+}
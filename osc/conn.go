@@ -0,0 +1,111 @@
+package osc
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// Conn sends and receives OSC packets on a single UDP socket. Unlike with a
+// Client and a Server, the packets are sent from the port on which the
+// replies are received, which many devices require.
+type Conn struct {
+	// ReadTimeout, Limits and ErrorHandler are used for receiving packets,
+	// see Server.
+	ReadTimeout  time.Duration
+	Limits       *Limits
+	ErrorHandler func(err error)
+
+	conn  net.PacketConn
+	raddr net.Addr
+}
+
+// NewConn returns a Conn that sends and receives packets on `c`. Send sends
+// the packets to `raddr`, which may be nil if only SendTo is used.
+func NewConn(c net.PacketConn, raddr net.Addr) *Conn {
+	return &Conn{conn: c, raddr: raddr}
+}
+
+// ListenConn returns a Conn that listens on the local UDP address `laddr`.
+// Use SendTo to send packets with it.
+func ListenConn(laddr string) (*Conn, error) {
+	c, err := net.ListenPacket("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c, nil), nil
+}
+
+// DialConn returns a Conn that listens on the local UDP address `laddr` and
+// sends packets to the UDP address `raddr` with Send. If `laddr` is empty, a
+// random local port is used.
+func DialConn(laddr, raddr string) (*Conn, error) {
+	addr, err := net.ResolveUDPAddr("udp", raddr)
+	if err != nil {
+		return nil, err
+	}
+	if laddr == "" {
+		laddr = ":0"
+	}
+	c, err := net.ListenPacket("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(c, addr), nil
+}
+
+// Send sends an OSC Bundle or an OSC Message to the remote address of the
+// Conn.
+func (c *Conn) Send(packet Packet) error {
+	if c.raddr == nil {
+		return errors.New("Send: no remote address")
+	}
+	return writePacketTo(c.conn, packet, c.raddr)
+}
+
+// SendTo sends an OSC Bundle or an OSC Message to `addr`.
+func (c *Conn) SendTo(packet Packet, addr net.Addr) error {
+	return writePacketTo(c.conn, packet, addr)
+}
+
+// Receive waits for the next OSC packet and returns it together with the
+// address of its sender.
+func (c *Conn) Receive() (Packet, net.Addr, error) {
+	return c.server(nil).ReceivePacketFrom(c.conn)
+}
+
+// Serve retrieves incoming OSC packets and dispatches them with `d`, like
+// Server.Serve. The handlers can reply with Reply, which sends the replies
+// over the Conn. If `d` is nil, a StandardDispatcher without handlers is
+// used. Serve shouldn't be used together with Receive.
+func (c *Conn) Serve(d Dispatcher) error {
+	if d == nil {
+		d = NewStandardDispatcher()
+	}
+	return c.server(d).Serve(c.conn)
+}
+
+// server returns a Server with the settings of the Conn.
+func (c *Conn) server(d Dispatcher) *Server {
+	return &Server{
+		Dispatcher:   d,
+		ReadTimeout:  c.ReadTimeout,
+		Limits:       c.Limits,
+		ErrorHandler: c.ErrorHandler,
+	}
+}
+
+// LocalAddr returns the local address of the Conn.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the address to which Send sends the packets, or nil.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.raddr
+}
+
+// Close closes the Conn. A running Serve or Receive returns an error.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
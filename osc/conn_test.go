@@ -0,0 +1,101 @@
+package osc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConn(t *testing.T) {
+	device, err := ListenConn("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer device.Close()
+
+	d := NewStandardDispatcher()
+	d.AddHandler("/fader", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+		Reply(ctx, NewMessage("/fader", float32(0.5)))
+	}))
+	go device.Serve(d)
+
+	c, err := DialConn("127.0.0.1:0", device.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.ReadTimeout = 5 * time.Second
+
+	if err = c.Send(NewMessage("/fader")); err != nil {
+		t.Fatal(err)
+	}
+	p, addr, err := c.Receive()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := addr.String(), device.LocalAddr().String(); got != want {
+		t.Errorf("Receive() address = %s, want = %s", got, want)
+	}
+	if got, want := p.(*Message).String(), "/fader ,f 0.5"; got != want {
+		t.Errorf("Receive() = %s, want = %s", got, want)
+	}
+
+	// The device can send to the Conn on its own as well.
+	if err = device.SendTo(NewMessage("/hello"), c.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+	if p, _, err = c.Receive(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.(*Message).Address, "/hello"; got != want {
+		t.Errorf("Receive() address = %s, want = %s", got, want)
+	}
+}
+
+func TestConnSendWithoutRemoteAddr(t *testing.T) {
+	c, err := ListenConn("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.RemoteAddr() != nil {
+		t.Errorf("RemoteAddr() = %s, want = nil", c.RemoteAddr())
+	}
+	if err = c.Send(NewMessage("/a")); err == nil {
+		t.Error("Send() expected an error")
+	}
+}
+
+func TestConnServeNilDispatcher(t *testing.T) {
+	c, err := ListenConn("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	c.ErrorHandler = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	done := make(chan error)
+	go func() { done <- c.Serve(nil) }()
+
+	sender, err := DialConn("127.0.0.1:0", c.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sender.Close()
+	if err = sender.Send(NewMessage("/a")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		t.Errorf("Serve(nil) reported %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	c.Close()
+	<-done
+}
@@ -0,0 +1,117 @@
+package osc
+
+import (
+	"context"
+	"net"
+	"runtime/debug"
+	"time"
+)
+
+// ContextDispatcher is a Dispatcher that takes a context with each packet.
+// Server passes the context to dispatchers that implement this interface, so
+// they can report the panics of handlers to Server.ErrorHandler.
+type ContextDispatcher interface {
+	Dispatcher
+	DispatchContext(ctx context.Context, packet Packet)
+}
+
+// dispatch dispatches `packet` with `d`, passing `ctx` if `d` is a
+// ContextDispatcher.
+func dispatch(ctx context.Context, d Dispatcher, packet Packet) {
+	if cd, ok := d.(ContextDispatcher); ok {
+		cd.DispatchContext(ctx, packet)
+		return
+	}
+	d.Dispatch(packet)
+}
+
+// contextKey is the type of the context keys of this package.
+type contextKey int
+
+const (
+	errorReporterKey contextKey = iota
+	messageInfoKey
+	inlineBundlesKey
+)
+
+// MessageInfo describes where and when a message was received. Server passes
+// it to the ContextHandlers in the context, use MessageInfoFromContext to get
+// it.
+type MessageInfo struct {
+	// RemoteAddr is the address of the sender.
+	RemoteAddr net.Addr
+	// LocalAddr is the local address on which the message was received.
+	LocalAddr net.Addr
+	// Conn is the connection on which the message was received, if it was
+	// received as a datagram.
+	Conn net.PacketConn
+	// Stream is the stream on which the message was received, if it was
+	// received on a stream such as a TCP connection.
+	Stream *StreamConn
+	// ReceivedAt is the time at which the packet was received.
+	ReceivedAt time.Time
+	// Timetag is the time tag of the bundle that contained the message. It
+	// is zero if the message wasn't part of a bundle.
+	Timetag Timetag
+}
+
+// MessageInfoFromContext returns the MessageInfo of the message that is
+// handled with `ctx`.
+func MessageInfoFromContext(ctx context.Context) (*MessageInfo, bool) {
+	info, ok := ctx.Value(messageInfoKey).(*MessageInfo)
+	return info, ok
+}
+
+// withMessageInfo returns a copy of `ctx` that carries `info`.
+func withMessageInfo(ctx context.Context, info *MessageInfo) context.Context {
+	return context.WithValue(ctx, messageInfoKey, info)
+}
+
+// withBundle returns a copy of `ctx` for the elements of the bundle `b`,
+// which carries the time tag of the bundle in its MessageInfo.
+func withBundle(ctx context.Context, b *Bundle) context.Context {
+	info := MessageInfo{}
+	if i, ok := MessageInfoFromContext(ctx); ok {
+		info = *i
+	}
+	info.Timetag = b.Timetag
+	return withMessageInfo(ctx, &info)
+}
+
+// withErrorReporter returns a copy of `ctx` that carries `report`. The panics
+// of handlers that are dispatched with the context are recovered and passed
+// to `report` as a *PanicError.
+func withErrorReporter(ctx context.Context, report func(err error)) context.Context {
+	return context.WithValue(ctx, errorReporterKey, report)
+}
+
+// withInlineBundles returns a copy of `ctx` with which the bundles that are
+// due are dispatched right away instead of by the scheduler, to keep them in
+// order with the packets received before and after them.
+func withInlineBundles(ctx context.Context) context.Context {
+	return context.WithValue(ctx, inlineBundlesKey, true)
+}
+
+// callHandler calls `h` with `msg`. If `ctx` carries an error reporter, a
+// panic of the handler is recovered and reported.
+func callHandler(ctx context.Context, h Handler, msg *Message) {
+	if report, ok := ctx.Value(errorReporterKey).(func(error)); ok {
+		defer func() {
+			if v := recover(); v != nil {
+				report(&PanicError{Address: msg.Address, Value: v, Stack: debug.Stack()})
+			}
+		}()
+	}
+	HandleContext(ctx, h, msg)
+}
+
+// HandleContext calls `h` with `ctx` and `msg` if it is a ContextHandler,
+// otherwise it calls `h` with `msg`. Middleware can use it to pass the
+// context on to the next handler.
+func HandleContext(ctx context.Context, h Handler, msg *Message) {
+	if ch, ok := h.(ContextHandler); ok {
+		ch.HandleMessageContext(ctx, msg)
+		return
+	}
+	h.HandleMessage(msg)
+}
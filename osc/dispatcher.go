@@ -3,9 +3,10 @@
 package osc
 
 import (
+	"context"
 	"errors"
-	"fmt"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,74 +32,339 @@ func (f HandlerFunc) HandleMessage(msg *Message) {
 	f(msg)
 }
 
+// ContextHandler is a Handler that also takes the context of the message. The
+// dispatchers call HandleMessageContext instead of HandleMessage. The context
+// carries the MessageInfo of messages that were received by a Server.
+type ContextHandler interface {
+	Handler
+	HandleMessageContext(ctx context.Context, msg *Message)
+}
+
+// ContextHandlerFunc implements the ContextHandler interface. Type definition
+// for an OSC handler function that takes the context of the message.
+type ContextHandlerFunc func(ctx context.Context, msg *Message)
+
+// HandleMessage calls itself with a background context and the given OSC
+// Message. Implements the Handler interface.
+func (f ContextHandlerFunc) HandleMessage(msg *Message) {
+	f(context.Background(), msg)
+}
+
+// HandleMessageContext calls itself with the given context and OSC Message.
+// Implements the ContextHandler interface.
+func (f ContextHandlerFunc) HandleMessageContext(ctx context.Context, msg *Message) {
+	f(ctx, msg)
+}
+
 // StandardDispatcher is a dispatcher for OSC packets. It handles the dispatching of
-// received OSC packets to Handlers for their given address.
+// received OSC packets to Handlers for their given address. Handlers can be
+// added and removed while packets are being dispatched.
 type StandardDispatcher struct {
-	handlers       map[string]Handler
-	defaultHandler Handler
+	// mu serializes the changes of the handlers.
+	mu sync.Mutex
+	// table holds the current *standardTable. It is replaced as a whole on
+	// every change, so dispatching doesn't need to lock.
+	table    atomic.Value
+	patterns *patternCache
+	// scheduler dispatches the bundles at the time of their time tags.
+	scheduler *scheduler
 }
 
-// NewStandardDispatcher returns an StandardDispatcher.
+// standardTable is an immutable set of handlers of a StandardDispatcher.
+type standardTable struct {
+	routes       map[string]*routeSet
+	defaultRoute *route
+	middleware   []Middleware
+}
+
+// NewStandardDispatcher returns an StandardDispatcher. The address patterns
+// of the dispatched messages are compiled once and kept in a cache of the
+// recently used ones.
 func NewStandardDispatcher() *StandardDispatcher {
-	return &StandardDispatcher{handlers: make(map[string]Handler)}
+	s := &StandardDispatcher{patterns: newPatternCache(defaultPatternCacheSize)}
+	s.table.Store(&standardTable{})
+	s.scheduler = newScheduler(s)
+	return s
 }
 
-// AddMsgHandler adds a new message handler for the given OSC address.
-func (s *StandardDispatcher) AddMsgHandler(addr string, handler HandlerFunc) error {
-	if addr == "*" {
-		s.defaultHandler = handler
-		return nil
+// AddMsgHandler adds a new message handler for the given OSC address. The
+// address "*" sets the default handler, which is called for every message.
+// The address may be followed by a space and a type tag pattern, e.g.
+// "/fader ,f", to only handle the messages whose type tags match it. A
+// handler without type tag pattern handles the messages that match none of
+// the patterns of the address. The handler is wrapped with `middleware`,
+// inside the middleware added with Use.
+func (s *StandardDispatcher) AddMsgHandler(addr string, handler HandlerFunc, middleware ...Middleware) error {
+	_, err := addRoute(s, addr, handler, middleware)
+	return err
+}
+
+// AddHandler adds a new handler for the given OSC address, like AddMsgHandler.
+// If the handler is a ContextHandler, it is called with the context of the
+// message.
+func (s *StandardDispatcher) AddHandler(addr string, handler Handler, middleware ...Middleware) error {
+	_, err := addRoute(s, addr, handler, middleware)
+	return err
+}
+
+// RemoveMsgHandler removes the message handler for the given OSC address.
+func (s *StandardDispatcher) RemoveMsgHandler(addr string) error {
+	return removeRoute(s, addr)
+}
+
+// ReplaceMsgHandler replaces the message handler for the given OSC address,
+// or adds it if there is none.
+func (s *StandardDispatcher) ReplaceMsgHandler(addr string, handler HandlerFunc, middleware ...Middleware) error {
+	return replaceRoute(s, addr, handler, middleware)
+}
+
+// Subscribe adds a new message handler for the given OSC address, like
+// AddMsgHandler, and returns a Subscription to remove it again.
+func (s *StandardDispatcher) Subscribe(addr string, handler HandlerFunc, middleware ...Middleware) (*Subscription, error) {
+	return addRoute(s, addr, handler, middleware)
+}
+
+// Use adds middleware that wraps all handlers, including the ones that were
+// added before and the default handler.
+func (s *StandardDispatcher) Use(middleware ...Middleware) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.table.Load().(*standardTable)
+	mw := append(t.middleware[:len(t.middleware):len(t.middleware)], middleware...)
+	routes := make(map[string]*routeSet, len(t.routes))
+	for addr, set := range t.routes {
+		routes[addr] = set.wrap(mw)
 	}
-	for _, chr := range "*?,[]{}# " {
-		if strings.Contains(addr, fmt.Sprintf("%c", chr)) {
-			return errors.New("OSC Address string may not contain any characters in \"*?,[]{}#")
-		}
+	var defaultRoute *route
+	if t.defaultRoute != nil {
+		defaultRoute = t.defaultRoute.wrap(mw)
 	}
+	s.table.Store(&standardTable{routes: routes, defaultRoute: defaultRoute, middleware: mw})
+}
+
+// updateRoute implements the routeUpdater interface.
+func (s *StandardDispatcher) updateRoute(key routeKey, fn func(old *route) (*route, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if addressExists(addr, s.handlers) {
-		return errors.New("OSC address exists already")
+	t := s.table.Load().(*standardTable)
+	if key.addr == "*" {
+		r, err := fn(t.defaultRoute)
+		if err != nil || r == t.defaultRoute {
+			return err
+		}
+		if r != nil {
+			r = r.wrap(t.middleware)
+		}
+		s.table.Store(&standardTable{routes: t.routes, defaultRoute: r, middleware: t.middleware})
+		return nil
 	}
 
-	s.handlers[addr] = handler
+	old := t.routes[key.addr].get(key.typeTags)
+	r, err := fn(old)
+	if err != nil || r == old {
+		return err
+	}
+	if r != nil {
+		r = r.wrap(t.middleware)
+	}
+	routes := make(map[string]*routeSet, len(t.routes)+1)
+	for addr, set := range t.routes {
+		routes[addr] = set
+	}
+	if set := t.routes[key.addr].with(key.typeTags, r); set == nil {
+		delete(routes, key.addr)
+	} else {
+		routes[key.addr] = set
+	}
+	s.table.Store(&standardTable{routes: routes, defaultRoute: t.defaultRoute, middleware: t.middleware})
 	return nil
 }
 
 // Dispatch dispatches OSC packets. Implements the Dispatcher interface.
 func (s *StandardDispatcher) Dispatch(packet Packet) {
+	s.DispatchContext(context.Background(), packet)
+}
+
+// DispatchContext dispatches OSC packets. If `ctx` comes from a Server, the
+// panics of the handlers are recovered and reported to its ErrorHandler.
+// Implements the ContextDispatcher interface.
+func (s *StandardDispatcher) DispatchContext(ctx context.Context, packet Packet) {
 	switch p := packet.(type) {
 	default:
 		return
 
 	case *Message:
-		for addr, handler := range s.handlers {
-			if p.Match(addr) {
-				handler.HandleMessage(p)
-			}
+		s.dispatchMessage(ctx, p)
+
+	case *Bundle:
+		s.scheduler.schedule(ctx, p)
+	}
+}
+
+// Stop stops dispatching bundles. The pending bundles are discarded, and the
+// bundles dispatched afterwards are dropped. Messages are still dispatched.
+func (s *StandardDispatcher) Stop() {
+	s.scheduler.stop()
+}
+
+// PendingBundles returns the number of bundles that wait for their time tag
+// or whose elements are being dispatched.
+func (s *StandardDispatcher) PendingBundles() int {
+	return s.scheduler.pending()
+}
+
+// SetLatePolicy sets what happens with the bundles that are dispatched more
+// than `tolerance` after their time tag. By default they are dispatched right
+// away.
+func (s *StandardDispatcher) SetLatePolicy(policy LatePolicy, tolerance time.Duration) {
+	s.scheduler.setLatePolicy(policy, tolerance)
+}
+
+// route is a handler that is registered for an OSC address.
+type route struct {
+	// sub identifies the registration of the handler, so a Subscription only
+	// removes the handler it added.
+	sub *Subscription
+	// typeTags is the type tag pattern of the route, and sig its parsed
+	// form. Both are empty for a route without type tag pattern.
+	typeTags string
+	sig      *signature
+	// base is the handler wrapped with the middleware of the route.
+	base Handler
+	// handler is base wrapped with the middleware of the dispatcher.
+	handler Handler
+}
+
+// routeUpdater is implemented by the dispatchers to change their handlers.
+type routeUpdater interface {
+	// updateRoute calls `fn` with the route for `key`, or nil if there is
+	// none, and replaces it with the returned route, wrapped with the
+	// middleware of the dispatcher. A nil route removes the handler. The
+	// address "*" refers to the default handler.
+	updateRoute(key routeKey, fn func(old *route) (*route, error)) error
+}
+
+// Subscription is a message handler that was added with Subscribe.
+type Subscription struct {
+	u   routeUpdater
+	key routeKey
+}
+
+// Unsubscribe removes the message handler from the dispatcher. It reports
+// whether the handler was removed; it isn't if it was already removed or
+// replaced. Unsubscribe can be called while packets are being dispatched.
+func (s *Subscription) Unsubscribe() bool {
+	removed := false
+	s.u.updateRoute(s.key, func(old *route) (*route, error) {
+		if old == nil || old.sub != s {
+			return old, nil
+		}
+		removed = true
+		return nil, nil
+	})
+	return removed
+}
+
+// addRoute adds `handler` for the OSC address `addr`. The address "*" sets
+// the default handler, which is called for every message.
+func addRoute(u routeUpdater, addr string, handler Handler, middleware []Middleware) (*Subscription, error) {
+	r, err := newRoute(u, addr, handler, middleware)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.updateRoute(r.sub.key, func(old *route) (*route, error) {
+		if old != nil && addr != "*" {
+			return nil, errors.New("OSC address exists already")
+		}
+		return r, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.sub, nil
+}
+
+// newRoute returns a route for `handler`, wrapped with `middleware`, under the
+// address `addr`, which may contain a type tag pattern.
+func newRoute(u routeUpdater, addr string, handler Handler, middleware []Middleware) (*route, error) {
+	key, sig, err := parseRouteKey(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &route{
+		sub:      &Subscription{u: u, key: key},
+		typeTags: key.typeTags,
+		sig:      sig,
+		base:     chain(middleware, handler),
+	}, nil
+}
+
+// removeRoute removes the handler for the OSC address `addr`.
+func removeRoute(u routeUpdater, addr string) error {
+	key, _, err := parseRouteKey(addr)
+	if err != nil {
+		return err
+	}
+	return u.updateRoute(key, func(old *route) (*route, error) {
+		if old == nil {
+			return nil, errors.New("OSC address doesn't exist")
 		}
-		if s.defaultHandler != nil {
-			s.defaultHandler.HandleMessage(p)
+		return nil, nil
+	})
+}
+
+// replaceRoute replaces the handler for the OSC address `addr`, or adds it if
+// there is none.
+func replaceRoute(u routeUpdater, addr string, handler Handler, middleware []Middleware) error {
+	r, err := newRoute(u, addr, handler, middleware)
+	if err != nil {
+		return err
+	}
+	return u.updateRoute(r.sub.key, func(old *route) (*route, error) {
+		return r, nil
+	})
+}
+
+// messageDispatcher is a ContextDispatcher that can dispatch a single
+// message.
+type messageDispatcher interface {
+	ContextDispatcher
+	dispatchMessage(ctx context.Context, msg *Message)
+}
+
+// dispatchElements dispatches the elements of the bundle `b` with `d`.
+// Messages are dispatched right away, nested bundles are dispatched as a
+// packet to respect their time tag.
+func dispatchElements(ctx context.Context, d messageDispatcher, b *Bundle) {
+	ctx = withBundle(ctx, b)
+	for _, element := range b.Elements {
+		switch e := element.(type) {
+		case *Message:
+			d.dispatchMessage(ctx, e)
+		case *Bundle:
+			d.DispatchContext(ctx, e)
 		}
+	}
+}
 
-	case *Bundle:
-		timer := time.NewTimer(p.Timetag.ExpiresIn())
-
-		go func() {
-			<-timer.C
-			for _, message := range p.Elements {
-				switch m := message.(type) {
-				case *Message:
-					for address, handler := range s.handlers {
-						if m.Match(address) {
-							handler.HandleMessage(m)
-						}
-					}
-					if s.defaultHandler != nil {
-						s.defaultHandler.HandleMessage(m)
-					}
-				case *Bundle:
-					s.Dispatch(m)
+// dispatchMessage calls the handlers whose address matches the address
+// pattern of `msg`, followed by the default handler.
+func (s *StandardDispatcher) dispatchMessage(ctx context.Context, msg *Message) {
+	t := s.table.Load().(*standardTable)
+	if pattern, err := s.patterns.get(msg.Address); err == nil {
+		tags := typeTags{msg: msg}
+		for addr, set := range t.routes {
+			if pattern.Match(addr) {
+				if r := set.match(&tags); r != nil {
+					callHandler(ctx, r.handler, msg)
 				}
 			}
-		}()
+		}
+	}
+	if t.defaultRoute != nil {
+		callHandler(ctx, t.defaultRoute.handler, msg)
 	}
 }
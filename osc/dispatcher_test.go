@@ -1,7 +1,9 @@
 package osc
 
 import (
+	"fmt"
 	"net"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -23,6 +25,16 @@ func TestAddMsgHandlerWithInvalidAddress(t *testing.T) {
 	}
 }
 
+func TestAddMsgHandlerWithoutSlash(t *testing.T) {
+	d := NewStandardDispatcher()
+	if err := d.AddMsgHandler("address/test", func(msg *Message) {}); err != nil {
+		t.Errorf("Expected that OSC address 'address/test' is valid, got %s", err)
+	}
+	if err := d.RemoveMsgHandler("address/test"); err != nil {
+		t.Errorf("Expected that OSC address 'address/test' can be removed, got %s", err)
+	}
+}
+
 func TestServerMessageDispatching(t *testing.T) {
 	finish := make(chan bool)
 	start := make(chan bool)
@@ -33,7 +45,8 @@ func TestServerMessageDispatching(t *testing.T) {
 	go func() {
 		conn, err := net.ListenPacket("udp", "localhost:6677")
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 		defer conn.Close()
 
@@ -83,3 +96,172 @@ func TestServerMessageDispatching(t *testing.T) {
 
 	done.Wait()
 }
+
+// testDispatcher is implemented by StandardDispatcher and TrieDispatcher.
+type testDispatcher interface {
+	ContextDispatcher
+	AddMsgHandler(addr string, handler HandlerFunc, middleware ...Middleware) error
+	AddHandler(addr string, handler Handler, middleware ...Middleware) error
+	RemoveMsgHandler(addr string) error
+	ReplaceMsgHandler(addr string, handler HandlerFunc, middleware ...Middleware) error
+	Subscribe(addr string, handler HandlerFunc, middleware ...Middleware) (*Subscription, error)
+	Use(middleware ...Middleware)
+	Stop()
+	PendingBundles() int
+	SetLatePolicy(policy LatePolicy, tolerance time.Duration)
+}
+
+var testDispatchers = []struct {
+	name string
+	new  func() testDispatcher
+}{
+	{"StandardDispatcher", func() testDispatcher { return NewStandardDispatcher() }},
+	{"TrieDispatcher", func() testDispatcher { return NewTrieDispatcher() }},
+}
+
+func TestDispatcherRemoveReplace(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var calls []string
+		handler := func(name string) HandlerFunc {
+			return func(msg *Message) { calls = append(calls, name) }
+		}
+		dispatch := func(addr string) string {
+			calls = nil
+			d.Dispatch(NewMessage(addr))
+			sort.Strings(calls)
+			return fmt.Sprint(calls)
+		}
+
+		d.AddMsgHandler("/a", handler("a"))
+		d.AddMsgHandler("/a/b", handler("b"))
+		if got, want := dispatch("//*"), "[a b]"; got != want {
+			t.Errorf("%s: handler calls = %s, want = %s", td.name, got, want)
+		}
+
+		if err := d.RemoveMsgHandler("/a/b"); err != nil {
+			t.Errorf("%s: RemoveMsgHandler() unexpected error: %s", td.name, err)
+		}
+		if err := d.RemoveMsgHandler("/a/b"); err == nil {
+			t.Errorf("%s: RemoveMsgHandler() of a removed handler expected an error", td.name)
+		}
+		if got, want := dispatch("//*"), "[a]"; got != want {
+			t.Errorf("%s: handler calls = %s, want = %s", td.name, got, want)
+		}
+
+		if err := d.ReplaceMsgHandler("/a", handler("a2")); err != nil {
+			t.Errorf("%s: ReplaceMsgHandler() unexpected error: %s", td.name, err)
+		}
+		if err := d.ReplaceMsgHandler("/c", handler("c")); err != nil {
+			t.Errorf("%s: ReplaceMsgHandler() unexpected error: %s", td.name, err)
+		}
+		if err := d.ReplaceMsgHandler("/c*", handler("c")); err == nil {
+			t.Errorf("%s: ReplaceMsgHandler() with an invalid address expected an error", td.name)
+		}
+		if got, want := dispatch("/*"), "[a2 c]"; got != want {
+			t.Errorf("%s: handler calls = %s, want = %s", td.name, got, want)
+		}
+
+		d.AddMsgHandler("*", handler("*"))
+		d.RemoveMsgHandler("*")
+		if got, want := dispatch("/a"), "[a2]"; got != want {
+			t.Errorf("%s: handler calls = %s, want = %s", td.name, got, want)
+		}
+	}
+}
+
+func TestDispatcherSubscribe(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		calls := 0
+		sub, err := d.Subscribe("/a", func(msg *Message) { calls++ })
+		if err != nil {
+			t.Fatalf("%s: Subscribe() unexpected error: %s", td.name, err)
+		}
+		if _, err = d.Subscribe("/a", func(msg *Message) {}); err == nil {
+			t.Errorf("%s: Subscribe() of an existing address expected an error", td.name)
+		}
+
+		d.Dispatch(NewMessage("/a"))
+		if !sub.Unsubscribe() {
+			t.Errorf("%s: Unsubscribe() = false, want = true", td.name)
+		}
+		if sub.Unsubscribe() {
+			t.Errorf("%s: second Unsubscribe() = true, want = false", td.name)
+		}
+		d.Dispatch(NewMessage("/a"))
+		if calls != 1 {
+			t.Errorf("%s: handler called %d times, want = 1", td.name, calls)
+		}
+
+		// A subscription doesn't remove a handler that replaced its own.
+		sub, _ = d.Subscribe("/a", func(msg *Message) {})
+		d.ReplaceMsgHandler("/a", func(msg *Message) { calls++ })
+		if sub.Unsubscribe() {
+			t.Errorf("%s: Unsubscribe() of a replaced handler = true, want = false", td.name)
+		}
+		d.Dispatch(NewMessage("/a"))
+		if calls != 2 {
+			t.Errorf("%s: handler called %d times, want = 2", td.name, calls)
+		}
+	}
+}
+
+func TestDispatcherConcurrentChanges(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		wg := sync.WaitGroup{}
+		stop := make(chan struct{})
+
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						d.Dispatch(NewMessage("//*"))
+					}
+				}
+			}()
+		}
+
+		for i := 0; i < 200; i++ {
+			addr := fmt.Sprintf("/ctl/%d", i%10)
+			d.ReplaceMsgHandler(addr, func(msg *Message) {})
+			if sub, err := d.Subscribe(addr+"/x", func(msg *Message) {}); err == nil {
+				sub.Unsubscribe()
+			}
+			d.RemoveMsgHandler(addr)
+		}
+		close(stop)
+		wg.Wait()
+	}
+}
+
+func TestDispatcherChangeFromHandler(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var sub *Subscription
+		sub, _ = d.Subscribe("/once", func(msg *Message) {
+			sub.Unsubscribe()
+			d.AddMsgHandler("/next", func(msg *Message) {})
+		})
+
+		done := make(chan struct{})
+		go func() {
+			d.Dispatch(NewMessage("/once"))
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: changing the handlers from a handler deadlocked", td.name)
+		}
+		if err := d.RemoveMsgHandler("/once"); err == nil {
+			t.Errorf("%s: handler wasn't unsubscribed", td.name)
+		}
+	}
+}
@@ -16,12 +16,24 @@ and other multimedia devices.
 Features:
 - Supports OSC messages with 'i' (Int32), 'f' (Float32),
  's' (string), 'b' (blob / binary data), 'h' (Int64), 't' (OSC timetag),
-  'd' (Double/int64), 'T' (True), 'F' (False), 'N' (Nil) types.
+  'd' (Double/int64), 'T' (True), 'F' (False), 'N' (Nil), 'c' (Char),
+  'r' (RGBA color), 'm' (MIDI message), 'S' (Symbol), 'I' (Impulse) types.
+  - Arrays ('[' ... ']'), including nested arrays
 - OSC bundles, including timetags
-- Support for OSC address pattern including '*', '?', '{,}' and '[]' wildcards
+- Support for OSC address pattern including '*', '?', '{,}', '[]' and '[!]'
+  wildcards, and the OSC 1.1 '//' path-traversing wildcard
+- UDP and TCP transports, and SLIP framed streams such as serial ports
+- A TrieDispatcher that stores the handlers in a tree of address parts, for
+  servers with many handlers
 
 This OSC implementation uses the UDP protocol for sending and receiving
-OSC packets.
+OSC packets by default. A Server with Network set to "tcp" (or ServeListener)
+receives them over TCP connections instead, and DialTCP returns a StreamConn
+that sends and receives them over a TCP connection. Encoder and Decoder
+read and write framed packets on any stream, using either the size-prefix
+framing of OSC 1.0 or the SLIP framing of OSC 1.1. Set Server.Framing to
+SLIPFraming, or use NewSLIPConn and DialSLIP, for SLIP streams such as
+serial ports; ServeStream serves them with a Server.
 
 The unit of transmission of OSC is an OSC Packet. Any application that sends
 OSC Packets is an OSC Client; any application that receives OSC Packets is
@@ -50,8 +62,15 @@ The following argument types are supported: 'i' (Int32), 'f' (Float32),
 's' (string), 'b' (blob / binary data), 'h' (Int64), 't' (OSC timetag),
 'd' (Double/int64), 'T' (True), 'F' (False), 'N' (Nil).
 
+The following OSC 1.1 and extended argument types are supported as well:
+'c' (Char), 'r' (RGBA), 'm' (MIDI), 'S' (Symbol), 'I' (Impulse).
+
+Arrays ('[' and ']' in the type tag string) are represented as
+[]interface{} arguments and may be nested.
+
 go-osc supports the following OSC address patterns:
-- '*', '?', '{,}' and '[]' wildcards.
+- '*', '?', '{,}', '[]' and '[!]' wildcards, which never match a '/'.
+- the OSC 1.1 '//' wildcard, which matches any number of address parts.
 
 Usage
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 )
 
 ////
@@ -16,39 +17,48 @@ const (
 	bit64Size     int = 8
 )
 
+// MaxStreamPacketSize is the default maximum size of the packets that are sent
+// and received on streams, which aren't limited by the size of a datagram like
+// MaxPacketSize.
+const MaxStreamPacketSize int = 16 << 20
+
 var padBytes = []byte{0, 0, 0, 0}
 
 // readBlob reads an OSC blob from the blob byte array. Padding bytes are
 // removed from the reader and not returned.
 func readBlob(reader *bytes.Buffer) ([]byte, int, error) {
 	// First, get the length
-	blobLen := int(binary.BigEndian.Uint32(reader.Next(4)))
-	n := 4 + blobLen
+	if reader.Len() < bit32Size {
+		return nil, 0, ErrTruncated
+	}
+	blobLen := int(int32(binary.BigEndian.Uint32(reader.Next(bit32Size))))
+	if blobLen < 0 {
+		return nil, 0, ErrBadLength
+	}
 
-	if blobLen < 1 || blobLen > reader.Len() {
-		return nil, 0, fmt.Errorf("readBlob: invalid blob length %d", blobLen)
+	numPadBytes := padBytesNeeded(blobLen)
+	if blobLen+numPadBytes > reader.Len() {
+		return nil, 0, ErrTruncated
 	}
 
 	// Read the data
 	blob := make([]byte, blobLen)
-	if _, err := reader.Read(blob); err != nil {
-		return nil, 0, fmt.Errorf("readBlob: %w", err)
-	}
+	copy(blob, reader.Next(blobLen))
 
 	// Remove the padding bytes
-	numPadBytes := padBytesNeeded(blobLen)
-	reader.Next(numPadBytes)
-
-	b := blob
+	if !isPadding(reader.Next(numPadBytes)) {
+		return nil, 0, ErrBadPadding
+	}
 
-	return b, n + numPadBytes, nil
+	return blob, bit32Size + blobLen + numPadBytes, nil
 }
 
 // writeBlob writes the data byte array as an OSC blob into buff. If the length
-// of data isn't 32-bit aligned, padding bytes will be added.
-func writeBlob(data []byte, buf *bytes.Buffer) (int, error) {
-	if len(data) > MaxPacketSize-4 {
-		return 0, fmt.Errorf("writeBlob: blob length greater than 65,531 bytes")
+// of data isn't 32-bit aligned, padding bytes will be added. Blobs that don't
+// fit in a packet of `maxSize` bytes are rejected.
+func writeBlob(data []byte, buf *bytes.Buffer, maxSize int) (int, error) {
+	if len(data) > maxSize-4 {
+		return 0, fmt.Errorf("writeBlob: blob length greater than %d bytes", maxSize-4)
 	}
 
 	// Add the size of the blob
@@ -67,7 +77,8 @@ func writeBlob(data []byte, buf *bytes.Buffer) (int, error) {
 }
 
 // readPaddedString reads a padded string from the given reader. The padding
-// bytes are removed from the reader.
+// bytes are removed from the reader. io.EOF is returned if the string isn't
+// terminated.
 func readPaddedString(reader *bytes.Buffer) (string, int, error) {
 	str, err := reader.ReadString(0)
 	if err != nil {
@@ -78,9 +89,16 @@ func readPaddedString(reader *bytes.Buffer) (string, int, error) {
 	str = str[:n-1]
 
 	// Remove the padding bytes
-	n += len(reader.Next(padBytesNeeded(n)))
+	numPadBytes := padBytesNeeded(n)
+	pad := reader.Next(numPadBytes)
+	if len(pad) < numPadBytes {
+		return "", 0, io.ErrUnexpectedEOF
+	}
+	if !isPadding(pad) {
+		return "", 0, ErrBadPadding
+	}
 
-	return str, n, nil
+	return str, n + numPadBytes, nil
 }
 
 // writePaddedString writes a string with padding bytes to the buffer.
@@ -100,6 +118,31 @@ func writePaddedString(str string, buf *bytes.Buffer) int {
 	return n + numPadBytes
 }
 
+// isPadding reports whether all bytes in `pad` are zero.
+func isPadding(pad []byte) bool {
+	for _, c := range pad {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// packetReader wraps the buffer an OSC packet is read from, to report the
+// offset of decoding errors and to enforce the limits.
+type packetReader struct {
+	*bytes.Buffer
+	size   int
+	limits *Limits
+	// args is the number of arguments read so far.
+	args int
+}
+
+// offset returns the offset of the next unread byte in the packet.
+func (r *packetReader) offset() int {
+	return r.size - r.Len()
+}
+
 // padBytesNeeded determines how many bytes are needed to fill up to the next 4
 // byte length.
 func padBytesNeeded(elementLen int) int {
@@ -0,0 +1,112 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Causes of a DecodeError. Use errors.Is to check for them.
+var (
+	// ErrTruncated is returned when the packet ends in the middle of an
+	// element.
+	ErrTruncated = errors.New("truncated packet")
+	// ErrBadTypeTag is returned for an invalid type tag string, an unknown
+	// type tag or unbalanced array brackets.
+	ErrBadTypeTag = errors.New("invalid type tag")
+	// ErrBadPadding is returned if the packet or one of its strings or blobs
+	// isn't padded with zeros to a multiple of 4 bytes.
+	ErrBadPadding = errors.New("invalid padding")
+	// ErrBadLength is returned for a negative or misaligned blob or bundle
+	// element length.
+	ErrBadLength = errors.New("invalid length")
+	// ErrBadAddress is returned if a packet doesn't start with an OSC address
+	// or bundle tag.
+	ErrBadAddress = errors.New("invalid address")
+	// ErrBadBundle is returned if a bundle doesn't start with "#bundle".
+	ErrBadBundle = errors.New("invalid bundle tag")
+	// ErrLimitExceeded is returned if a packet exceeds one of the Limits.
+	ErrLimitExceeded = errors.New("limit exceeded")
+)
+
+// DecodeError describes why and where an OSC packet couldn't be decoded.
+type DecodeError struct {
+	// Offset is the position in the packet, in bytes, of the element that
+	// couldn't be decoded.
+	Offset int
+	// TypeTag is the type tag of the argument being decoded, or 0 if the
+	// error didn't occur while decoding an argument.
+	TypeTag byte
+	// Err is the cause of the error, one of the Err* variables above.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *DecodeError) Error() string {
+	if e.TypeTag != 0 {
+		return fmt.Sprintf("decode error at offset %d (type tag '%c'): %s", e.Offset, e.TypeTag, e.Err)
+	}
+	return fmt.Sprintf("decode error at offset %d: %s", e.Offset, e.Err)
+}
+
+// Unwrap returns the cause of the error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// decodeCause maps the errors of the low level read functions to the causes
+// of a DecodeError.
+func decodeCause(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
+
+// PanicError is reported to Server.ErrorHandler if a handler panics.
+type PanicError struct {
+	// Address is the address of the message that was handled.
+	Address string
+	// Value is the value that was passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the handler's goroutine at the panic.
+	Stack []byte
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic while handling %s: %v", e.Address, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// PacketError describes a received packet that couldn't be decoded. Server
+// reports it instead of returning from Serve, and ReceivePacket returns it.
+type PacketError struct {
+	// Data is a copy of the raw bytes of the packet. It is nil if the
+	// packet was skipped by the Framing of a stream.
+	Data []byte
+	// Addr is the address of the sender.
+	Addr net.Addr
+	// Err is the reason why the packet couldn't be decoded, usually a
+	// *DecodeError.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *PacketError) Error() string {
+	if e.Addr == nil {
+		return fmt.Sprintf("bad packet: %s", e.Err)
+	}
+	return fmt.Sprintf("bad packet from %s: %s", e.Addr, e.Err)
+}
+
+// Unwrap returns the reason of the error.
+func (e *PacketError) Unwrap() error {
+	return e.Err
+}
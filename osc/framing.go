@@ -0,0 +1,202 @@
+package osc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+////
+// Stream framing
+////
+
+// Framing delimits OSC packets on a byte stream, such as a TCP connection, a
+// file or a pipe.
+type Framing interface {
+	// ReadFrame reads the next frame from `r` and appends its contents to
+	// `buf`. It returns io.EOF if the stream ends before a frame starts, and
+	// io.ErrUnexpectedEOF if it ends in the middle of a frame. Frames larger
+	// than `maxSize` bytes are rejected with ErrFrameTooLarge. If an invalid
+	// or too large frame is skipped, so the following frames can still be
+	// read, a *FrameError is returned.
+	ReadFrame(r *bufio.Reader, buf []byte, maxSize int) ([]byte, error)
+	// WriteFrame writes `data` as a single frame to `w`.
+	WriteFrame(w io.Writer, data []byte) error
+}
+
+var (
+	// SizePrefixFraming precedes each packet with its size as a big-endian
+	// int32, as specified by OSC 1.0 for stream transports.
+	SizePrefixFraming Framing = sizePrefixFraming{}
+
+	// SLIPFraming encloses each packet in SLIP (RFC 1055) END bytes, as
+	// specified by OSC 1.1 for stream transports.
+	SLIPFraming Framing = slipFraming{}
+)
+
+// ErrFrameTooLarge is returned when a frame exceeds the maximum packet size.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// FrameError is returned by Framing.ReadFrame if a frame was skipped because
+// it was invalid or too large. The stream can be read on.
+type FrameError struct {
+	// Err is the reason why the frame was skipped.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *FrameError) Error() string {
+	return fmt.Sprintf("ReadFrame: skipped frame: %s", e.Err)
+}
+
+// Unwrap returns the reason of the error.
+func (e *FrameError) Unwrap() error {
+	return e.Err
+}
+
+type sizePrefixFraming struct{}
+
+// ReadFrame implements the Framing interface.
+func (sizePrefixFraming) ReadFrame(r *bufio.Reader, buf []byte, maxSize int) ([]byte, error) {
+	var size [bit32Size]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return buf, err
+	}
+
+	n := int(int32(binary.BigEndian.Uint32(size[:])))
+	if n < 0 {
+		return buf, fmt.Errorf("ReadFrame: invalid frame size: %d", n)
+	}
+	if n > maxSize {
+		return buf, fmt.Errorf("ReadFrame: %w: %d", ErrFrameTooLarge, n)
+	}
+
+	start := len(buf)
+	if cap(buf)-start < n {
+		buf = append(make([]byte, 0, start+n), buf...)
+	}
+	buf = buf[:start+n]
+	if _, err := io.ReadFull(r, buf[start:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf[:start], err
+	}
+
+	return buf, nil
+}
+
+// WriteFrame implements the Framing interface.
+func (sizePrefixFraming) WriteFrame(w io.Writer, data []byte) error {
+	if len(data) > math.MaxInt32 {
+		return fmt.Errorf("WriteFrame: %w: %d", ErrFrameTooLarge, len(data))
+	}
+	frame := make([]byte, bit32Size, bit32Size+len(data))
+	binary.BigEndian.PutUint32(frame, uint32(len(data)))
+	_, err := w.Write(append(frame, data...))
+	return err
+}
+
+// SLIP special characters.
+const (
+	slipEnd    byte = 0xC0
+	slipEsc    byte = 0xDB
+	slipEscEnd byte = 0xDC
+	slipEscEsc byte = 0xDD
+)
+
+type slipFraming struct{}
+
+// ReadFrame implements the Framing interface. Empty frames, as produced by
+// the double END encoding, are skipped. Frames with an invalid escape sequence
+// or more than `maxSize` bytes are skipped up to the next END byte, which
+// recovers from a sender that starts a new frame before ending the last one.
+func (slipFraming) ReadFrame(r *bufio.Reader, buf []byte, maxSize int) ([]byte, error) {
+	start := len(buf)
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(buf) > start {
+				err = io.ErrUnexpectedEOF
+			}
+			return buf[:start], err
+		}
+
+		switch c {
+		case slipEnd:
+			if len(buf) > start {
+				return buf, nil
+			}
+			continue
+
+		case slipEsc:
+			if c, err = r.ReadByte(); err != nil {
+				if errors.Is(err, io.EOF) {
+					err = io.ErrUnexpectedEOF
+				}
+				return buf[:start], err
+			}
+			switch c {
+			case slipEscEnd:
+				c = slipEnd
+			case slipEscEsc:
+				c = slipEsc
+			case slipEnd:
+				// The END byte ends the invalid frame.
+				return buf[:start], &FrameError{Err: errors.New("invalid SLIP escape sequence at end of frame")}
+			default:
+				if err := skipSLIPFrame(r); err != nil {
+					return buf[:start], err
+				}
+				return buf[:start], &FrameError{Err: fmt.Errorf("invalid SLIP escape sequence: %#x", c)}
+			}
+		}
+
+		if len(buf)-start >= maxSize {
+			if err := skipSLIPFrame(r); err != nil {
+				return buf[:start], err
+			}
+			return buf[:start], &FrameError{Err: ErrFrameTooLarge}
+		}
+		buf = append(buf, c)
+	}
+}
+
+// skipSLIPFrame discards the rest of a frame up to and including the next END
+// byte. It returns io.ErrUnexpectedEOF if the stream ends first.
+func skipSLIPFrame(r *bufio.Reader) error {
+	for {
+		_, err := r.ReadSlice(slipEnd)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, io.EOF):
+			return io.ErrUnexpectedEOF
+		case err != bufio.ErrBufferFull:
+			return err
+		}
+	}
+}
+
+// WriteFrame implements the Framing interface.
+func (slipFraming) WriteFrame(w io.Writer, data []byte) error {
+	frame := make([]byte, 0, len(data)+2)
+	frame = append(frame, slipEnd)
+	for _, c := range data {
+		switch c {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, c)
+		}
+	}
+	frame = append(frame, slipEnd)
+
+	_, err := w.Write(frame)
+	return err
+}
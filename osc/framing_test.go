@@ -0,0 +1,122 @@
+package osc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestSizePrefixFraming(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		in   []byte
+		want []byte
+		err  error
+	}{
+		{"frame", []byte{0, 0, 0, 4, 1, 2, 3, 4}, []byte{1, 2, 3, 4}, nil},
+		{"empty_frame", []byte{0, 0, 0, 0}, []byte{}, nil},
+		{"eof", []byte{}, []byte{}, io.EOF},
+		{"short_size", []byte{0, 0}, []byte{}, io.ErrUnexpectedEOF},
+		{"short_data", []byte{0, 0, 0, 4, 1, 2}, []byte{}, io.ErrUnexpectedEOF},
+		{"too_large", []byte{0, 0, 0, 9, 1, 2, 3, 4, 5, 6, 7, 8, 9}, []byte{}, ErrFrameTooLarge},
+	} {
+		got, err := SizePrefixFraming.ReadFrame(bufio.NewReader(bytes.NewReader(tt.in)), []byte{}, 8)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: ReadFrame() error = %v, want = %v", tt.desc, err, tt.err)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: ReadFrame() = %v, want = %v", tt.desc, got, tt.want)
+		}
+	}
+
+	if _, err := SizePrefixFraming.ReadFrame(bufio.NewReader(bytes.NewReader([]byte{255, 255, 255, 255})), nil, 8); err == nil {
+		t.Error("negative size: ReadFrame() expected an error")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := SizePrefixFraming.WriteFrame(buf, []byte{1, 2, 3, 4}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.Bytes(), []byte{0, 0, 0, 4, 1, 2, 3, 4}; !bytes.Equal(got, want) {
+		t.Errorf("WriteFrame() = %v, want = %v", got, want)
+	}
+}
+
+func TestSLIPFraming(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		in   []byte
+		want [][]byte
+		err  error
+	}{
+		{"frame", []byte{slipEnd, 1, 2, slipEnd}, [][]byte{{1, 2}}, io.EOF},
+		{"no_leading_end", []byte{1, 2, slipEnd}, [][]byte{{1, 2}}, io.EOF},
+		{"escapes", []byte{slipEnd, slipEsc, slipEscEnd, slipEsc, slipEscEsc, slipEnd}, [][]byte{{slipEnd, slipEsc}}, io.EOF},
+		{"two_frames", []byte{slipEnd, 1, slipEnd, slipEnd, 2, slipEnd}, [][]byte{{1}, {2}}, io.EOF},
+		{"partial", []byte{slipEnd, 1, 2}, nil, io.ErrUnexpectedEOF},
+		{"partial_escape", []byte{slipEnd, 1, slipEsc}, nil, io.ErrUnexpectedEOF},
+		{"too_large", []byte{slipEnd, 1, 2, 3, 4, 5, 6, 7, 8, 9, slipEnd}, nil, ErrFrameTooLarge},
+	} {
+		r := bufio.NewReader(bytes.NewReader(tt.in))
+		var got [][]byte
+		var err error
+		for {
+			var frame []byte
+			if frame, err = SLIPFraming.ReadFrame(r, nil, 8); err != nil {
+				break
+			}
+			got = append(got, frame)
+		}
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: ReadFrame() error = %v, want = %v", tt.desc, err, tt.err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ReadFrame() = %v, want = %v", tt.desc, got, tt.want)
+		}
+	}
+
+	if _, err := SLIPFraming.ReadFrame(bufio.NewReader(bytes.NewReader([]byte{slipEsc, 1, slipEnd})), nil, 8); err == nil {
+		t.Error("invalid escape: ReadFrame() expected an error")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := SLIPFraming.WriteFrame(buf, []byte{1, slipEnd, 2, slipEsc}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.Bytes(), []byte{slipEnd, 1, slipEsc, slipEscEnd, 2, slipEsc, slipEscEsc, slipEnd}; !bytes.Equal(got, want) {
+		t.Errorf("WriteFrame() = %v, want = %v", got, want)
+	}
+}
+
+func TestSLIPFramingRecovery(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		in   []byte
+	}{
+		{"invalid_escape", []byte{slipEnd, 1, slipEsc, 1, 2, slipEnd, 3, 4, slipEnd}},
+		{"escape_before_end", []byte{slipEnd, 1, slipEsc, slipEnd, 3, 4, slipEnd}},
+		{"too_large", []byte{slipEnd, 1, 2, 3, 4, 5, 6, 7, 8, 9, slipEsc, slipEscEnd, slipEnd, 3, 4, slipEnd}},
+	} {
+		r := bufio.NewReader(bytes.NewReader(tt.in))
+		var fe *FrameError
+		if _, err := SLIPFraming.ReadFrame(r, nil, 8); !errors.As(err, &fe) {
+			t.Errorf("%s: ReadFrame() error = %v, want a *FrameError", tt.desc, err)
+			continue
+		}
+		got, err := SLIPFraming.ReadFrame(r, nil, 8)
+		if err != nil || !bytes.Equal(got, []byte{3, 4}) {
+			t.Errorf("%s: ReadFrame() after the skipped frame = %v, %v, want = [3 4]", tt.desc, got, err)
+		}
+	}
+
+	// A skipped frame that isn't ended is truncated.
+	r := bufio.NewReader(bytes.NewReader([]byte{slipEnd, 1, slipEsc, 1, 2}))
+	if _, err := SLIPFraming.ReadFrame(r, nil, 8); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFrame() error = %v, want = %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build gofuzz
+// +build gofuzz
+
+package osc
+
+// Fuzz is the entry point for go-fuzz (github.com/dvyukov/go-fuzz). Decoding
+// must never panic, and every packet that decodes must encode again.
+func Fuzz(data []byte) int {
+	p, err := ReadPacket(data)
+	if err != nil {
+		return 0
+	}
+	if _, err = p.MarshalBinary(); err != nil {
+		return 0
+	}
+	return 1
+}
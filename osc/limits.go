@@ -0,0 +1,50 @@
+package osc
+
+// Limits restricts the resources used to decode a packet, so a hostile sender
+// can't force deep recursion or huge allocations. A zero field means that the
+// corresponding value isn't limited. Packets that exceed a limit are rejected
+// with a *DecodeError whose cause is ErrLimitExceeded.
+type Limits struct {
+	// MaxBundleDepth is the maximum nesting depth of bundles. A bundle that
+	// isn't part of another bundle has a depth of 1.
+	MaxBundleDepth int
+	// MaxBundleElements is the maximum number of elements in a bundle.
+	MaxBundleElements int
+	// MaxArguments is the maximum number of arguments of a message,
+	// including the elements of arrays.
+	MaxArguments int
+	// MaxArrayDepth is the maximum nesting depth of array arguments.
+	MaxArrayDepth int
+	// MaxStringLength is the maximum length of a string or symbol argument
+	// in bytes.
+	MaxStringLength int
+	// MaxBlobLength is the maximum length of a blob argument in bytes.
+	MaxBlobLength int
+	// MaxAddressLength is the maximum length of an OSC address in bytes.
+	MaxAddressLength int
+}
+
+// DefaultLimits are the limits that are enforced if no other limits are
+// given, e.g. by ReadPacket and UnmarshalBinary.
+var DefaultLimits = Limits{
+	MaxBundleDepth:    16,
+	MaxBundleElements: 4096,
+	MaxArguments:      4096,
+	MaxArrayDepth:     16,
+	MaxStringLength:   MaxPacketSize,
+	MaxBlobLength:     MaxPacketSize,
+	MaxAddressLength:  1024,
+}
+
+// orDefault returns `l`, or DefaultLimits if `l` is nil.
+func (l *Limits) orDefault() *Limits {
+	if l == nil {
+		return &DefaultLimits
+	}
+	return l
+}
+
+// exceeds reports whether `n` exceeds the limit `max`.
+func exceeds(n, max int) bool {
+	return max > 0 && n > max
+}
@@ -0,0 +1,83 @@
+package osc
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// nestBundles returns a bundle that is nested `depth` bundles deep.
+func nestBundles(depth int) *Bundle {
+	b := NewBundle(time.Unix(0, 0))
+	b.Append(NewMessage("/a"))
+	for i := 1; i < depth; i++ {
+		outer := NewBundle(time.Unix(0, 0))
+		outer.Append(b)
+		b = outer
+	}
+	return b
+}
+
+func TestReadPacketWithLimits(t *testing.T) {
+	wide := NewBundle(time.Unix(0, 0))
+	for i := 0; i < 5; i++ {
+		wide.Append(NewMessage("/a"))
+	}
+
+	for _, tt := range []struct {
+		desc   string
+		pkt    Packet
+		limits Limits
+		ok     bool
+	}{
+		{"bundle_depth_ok", nestBundles(3), Limits{MaxBundleDepth: 3}, true},
+		{"bundle_depth", nestBundles(4), Limits{MaxBundleDepth: 3}, false},
+		{"bundle_elements_ok", wide, Limits{MaxBundleElements: 5}, true},
+		{"bundle_elements", wide, Limits{MaxBundleElements: 4}, false},
+		{"arguments_ok", NewMessage("/a", int32(1), []interface{}{int32(2)}), Limits{MaxArguments: 3}, true},
+		{"arguments", NewMessage("/a", int32(1), []interface{}{int32(2), int32(3)}), Limits{MaxArguments: 3}, false},
+		{"array_depth_ok", NewMessage("/a", []interface{}{[]interface{}{}}), Limits{MaxArrayDepth: 2}, true},
+		{"array_depth", NewMessage("/a", []interface{}{[]interface{}{[]interface{}{}}}), Limits{MaxArrayDepth: 2}, false},
+		{"string_ok", NewMessage("/a", "abcd"), Limits{MaxStringLength: 4}, true},
+		{"string", NewMessage("/a", "abcde"), Limits{MaxStringLength: 4}, false},
+		{"symbol", NewMessage("/a", Symbol("abcde")), Limits{MaxStringLength: 4}, false},
+		{"blob_ok", NewMessage("/a", []byte{1, 2, 3, 4}), Limits{MaxBlobLength: 4}, true},
+		{"blob", NewMessage("/a", []byte{1, 2, 3, 4, 5}), Limits{MaxBlobLength: 4}, false},
+		{"address_ok", NewMessage("/abc"), Limits{MaxAddressLength: 4}, true},
+		{"address", NewMessage("/abcd"), Limits{MaxAddressLength: 4}, false},
+		{"unlimited", nestBundles(32), Limits{}, true},
+	} {
+		data, err := tt.pkt.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = ReadPacketWithLimits(data, &tt.limits)
+		if err != nil && tt.ok {
+			t.Errorf("%s: ReadPacketWithLimits() unexpected error: %s", tt.desc, err)
+		}
+		if err == nil && !tt.ok {
+			t.Errorf("%s: ReadPacketWithLimits() expected an error", tt.desc)
+		}
+		if !tt.ok && !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("%s: ReadPacketWithLimits() error = %v, want = %v", tt.desc, err, ErrLimitExceeded)
+		}
+	}
+}
+
+func TestDefaultLimits(t *testing.T) {
+	data, err := nestBundles(DefaultLimits.MaxBundleDepth + 1).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = ReadPacket(data); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ReadPacket() error = %v, want = %v", err, ErrLimitExceeded)
+	}
+	if err = new(Bundle).UnmarshalBinary(data); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("UnmarshalBinary() error = %v, want = %v", err, ErrLimitExceeded)
+	}
+	if _, err = ReadPacketWithLimits(data, nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ReadPacketWithLimits(nil) error = %v, want = %v", err, ErrLimitExceeded)
+	}
+}
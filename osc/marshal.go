@@ -0,0 +1,268 @@
+package osc
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+////
+// Struct binding
+////
+
+// Marshal returns a new Message with the OSC address `addr`, whose arguments
+// are the fields of the struct `v` (or a pointer to one).
+//
+// The exported fields of the struct are mapped to positional arguments in the
+// order they are declared. Their types must be types that GetTypeTag
+// understands, or interface{}. The mapping can be customized with the "osc"
+// key in the struct field's tag, which holds an optional name followed by a
+// comma-separated list of options:
+//
+//	// Field is ignored.
+//	Field int32 `osc:"-"`
+//
+//	// Field may be missing from the message. Only the trailing fields of
+//	// a struct may be optional. Marshal omits trailing optional fields
+//	// that hold their zero value.
+//	Field int32 `osc:"field,optional"`
+//
+//	// Field is a slice that takes all remaining arguments. Only the last
+//	// field of a struct may be variadic.
+//	Field []string `osc:",variadic"`
+//
+// The name is only used in error messages.
+func Marshal(addr string, v interface{}) (*Message, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Marshal: unsupported type: %T", v)
+	}
+
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return nil, fmt.Errorf("Marshal: %w", err)
+	}
+
+	args := make([]interface{}, 0, len(info.fields))
+	// keep is the number of arguments that can't be omitted.
+	keep := 0
+	for _, f := range info.fields {
+		fv := rv.Field(f.index)
+		if f.variadic {
+			for i := 0; i < fv.Len(); i++ {
+				args = append(args, fv.Index(i).Interface())
+			}
+			keep = len(args)
+			continue
+		}
+
+		args = append(args, fv.Interface())
+		if !f.optional || !fv.IsZero() {
+			keep = len(args)
+		}
+	}
+
+	msg := NewMessage(addr)
+	if err = msg.Append(args[:keep]...); err != nil {
+		return nil, fmt.Errorf("Marshal: %w", err)
+	}
+	return msg, nil
+}
+
+// Unmarshal stores the arguments of the OSC message `msg` in the fields of
+// the struct pointed to by `v`. The fields are mapped to the arguments as
+// described for Marshal, and converted as described for Message.Scan. An
+// error is returned if an argument can't be stored in its field, if a
+// required argument is missing or if there are more arguments than fields.
+func Unmarshal(msg *Message, v interface{}) error {
+	if msg == nil {
+		return fmt.Errorf("Unmarshal: message is nil")
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unmarshal: unsupported type: %T", v)
+	}
+	rv = rv.Elem()
+
+	info, err := getStructInfo(rv.Type())
+	if err != nil {
+		return fmt.Errorf("Unmarshal: %w", err)
+	}
+
+	args := msg.Arguments
+	for i, f := range info.fields {
+		fv := rv.Field(f.index)
+		if f.variadic {
+			s := reflect.MakeSlice(fv.Type(), 0, 0)
+			for j := i; j < len(args); j++ {
+				e := reflect.New(fv.Type().Elem()).Elem()
+				if err := setArg(e, args[j]); err != nil {
+					return fmt.Errorf("Unmarshal: argument %d (%s): %w", j, f.name, err)
+				}
+				s = reflect.Append(s, e)
+			}
+			fv.Set(s)
+			return nil
+		}
+
+		if i >= len(args) {
+			if f.optional {
+				continue
+			}
+			return fmt.Errorf("Unmarshal: missing argument %d (%s)", i, f.name)
+		}
+		if err := setArg(fv, args[i]); err != nil {
+			return fmt.Errorf("Unmarshal: argument %d (%s): %w", i, f.name, err)
+		}
+	}
+
+	if len(args) > len(info.fields) {
+		return fmt.Errorf("Unmarshal: too many arguments: %d, want at most %d", len(args), len(info.fields))
+	}
+	return nil
+}
+
+// setArg stores the OSC argument `arg` in `v`. If the argument's type isn't
+// assignable to `v`, the widening conversions described for Message.Scan are
+// tried.
+func setArg(v reflect.Value, arg interface{}) error {
+	if arg == nil {
+		if v.Kind() != reflect.Interface {
+			return fmt.Errorf("can't store Nil in %s", v.Type())
+		}
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	av := reflect.ValueOf(arg)
+	if av.Type().AssignableTo(v.Type()) {
+		v.Set(av)
+		return nil
+	}
+
+	if w := widenArg(arg); w != nil {
+		if wv := reflect.ValueOf(w); wv.Type().AssignableTo(v.Type()) {
+			v.Set(wv)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("can't store %s in %s", av.Type(), v.Type())
+}
+
+// widenArg returns the wider type of the argument `arg`, or nil if there is
+// none.
+func widenArg(arg interface{}) interface{} {
+	switch t := arg.(type) {
+	case int32:
+		return int64(t)
+	case float32:
+		return float64(t)
+	case Symbol:
+		return string(t)
+	default:
+		return nil
+	}
+}
+
+// structInfo describes how the fields of a struct type map to OSC arguments.
+type structInfo struct {
+	fields []fieldInfo
+}
+
+// fieldInfo describes a single struct field that maps to an OSC argument.
+type fieldInfo struct {
+	name     string
+	index    int
+	optional bool
+	variadic bool
+}
+
+// structInfoCache maps a reflect.Type to its *structInfo, or the error that
+// occurred while analyzing it.
+var structInfoCache sync.Map
+
+// getStructInfo returns the (cached) structInfo for the struct type `t`.
+func getStructInfo(t reflect.Type) (*structInfo, error) {
+	if v, ok := structInfoCache.Load(t); ok {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v.(*structInfo), nil
+	}
+
+	info, err := newStructInfo(t)
+	if err != nil {
+		structInfoCache.Store(t, err)
+		return nil, err
+	}
+	structInfoCache.Store(t, info)
+	return info, nil
+}
+
+// newStructInfo analyzes the fields and "osc" tags of the struct type `t`.
+func newStructInfo(t reflect.Type) (*structInfo, error) {
+	info := &structInfo{}
+	optional := false
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("osc")
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+
+		f := fieldInfo{name: sf.Name, index: i}
+		opts := strings.Split(tag, ",")
+		if opts[0] != "" {
+			f.name = opts[0]
+		}
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "optional":
+				f.optional = true
+			case "variadic":
+				f.variadic = true
+			default:
+				return nil, fmt.Errorf("field %s: unknown option: %q", sf.Name, opt)
+			}
+		}
+
+		if len(info.fields) > 0 && info.fields[len(info.fields)-1].variadic {
+			return nil, fmt.Errorf("field %s: only the last field may be variadic", sf.Name)
+		}
+		if optional && !f.optional && !f.variadic {
+			return nil, fmt.Errorf("field %s: required field follows an optional field", sf.Name)
+		}
+		optional = optional || f.optional
+
+		ft := sf.Type
+		if f.variadic {
+			if ft.Kind() != reflect.Slice {
+				return nil, fmt.Errorf("field %s: variadic field must be a slice", sf.Name)
+			}
+			ft = ft.Elem()
+		}
+		if !isArgType(ft) {
+			return nil, fmt.Errorf("field %s: unsupported type: %s", sf.Name, ft)
+		}
+
+		info.fields = append(info.fields, f)
+	}
+
+	return info, nil
+}
+
+// isArgType reports whether values of the type `t` can be OSC arguments.
+func isArgType(t reflect.Type) bool {
+	if t.Kind() == reflect.Interface {
+		return t.NumMethod() == 0
+	}
+	_, err := GetTypeTag(reflect.Zero(t).Interface())
+	return err == nil
+}
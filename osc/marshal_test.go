@@ -0,0 +1,132 @@
+package osc
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fader struct {
+	Channel int32
+	Level   float32
+	Label   string `osc:"label,optional"`
+	ignored bool
+	Skip    bool `osc:"-"`
+}
+
+type note struct {
+	Name string
+	Tags []interface{} `osc:",variadic"`
+}
+
+type wide struct {
+	I int64
+	F float64
+	S string
+}
+
+type badOptional struct {
+	A int32 `osc:",optional"`
+	B int32
+}
+
+type badVariadic struct {
+	A []int32 `osc:",variadic"`
+	B int32
+}
+
+type badType struct {
+	A int
+}
+
+func TestMarshal(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		v    interface{}
+		want *Message
+		ok   bool
+	}{
+		{"struct", fader{1, 0.5, "main", true, true}, NewMessage("/f", int32(1), float32(0.5), "main"), true},
+		{"pointer", &fader{Channel: 2, Level: 1}, NewMessage("/f", int32(2), float32(1)), true},
+		{"variadic", note{"a", []interface{}{int32(1), "b"}}, NewMessage("/f", "a", int32(1), "b"), true},
+		{"empty_variadic", note{Name: "a"}, NewMessage("/f", "a"), true},
+		{"not_struct", int32(1), nil, false},
+		{"bad_optional", badOptional{}, nil, false},
+		{"bad_variadic", badVariadic{}, nil, false},
+		{"bad_type", badType{}, nil, false},
+		{"bad_variadic_value", note{"a", []interface{}{1}}, nil, false},
+	} {
+		got, err := Marshal("/f", tt.v)
+		if err != nil && tt.ok {
+			t.Errorf("%s: Marshal() unexpected error: %s", tt.desc, err)
+			continue
+		}
+		if err == nil && !tt.ok {
+			t.Errorf("%s: Marshal() expected an error", tt.desc)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Marshal() = %v, want = %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		msg  *Message
+		v    interface{}
+		want interface{}
+		ok   bool
+	}{
+		{"all", NewMessage("/f", int32(1), float32(0.5), "main"), &fader{}, &fader{1, 0.5, "main", false, false}, true},
+		{"optional_missing", NewMessage("/f", int32(1), float32(0.5)), &fader{}, &fader{Channel: 1, Level: 0.5}, true},
+		{"required_missing", NewMessage("/f", int32(1)), &fader{}, nil, false},
+		{"too_many", NewMessage("/f", int32(1), float32(0.5), "main", true), &fader{}, nil, false},
+		{"wrong_type", NewMessage("/f", float32(1), float32(0.5)), &fader{}, nil, false},
+		{"nil_arg", NewMessage("/f", nil, float32(0.5)), &fader{}, nil, false},
+		{"variadic", NewMessage("/f", "a", nil, true), &note{}, &note{"a", []interface{}{nil, true}}, true},
+		{"empty_variadic", NewMessage("/f", "a"), &note{}, &note{"a", []interface{}{}}, true},
+		{"widening", NewMessage("/f", int32(1), float32(2), Symbol("s")), &wide{}, &wide{1, 2, "s"}, true},
+		{"nil_message", nil, &fader{}, nil, false},
+		{"not_pointer", NewMessage("/f"), fader{}, nil, false},
+		{"nil_pointer", NewMessage("/f"), (*fader)(nil), nil, false},
+		{"bad_optional", NewMessage("/f"), &badOptional{}, nil, false},
+	} {
+		err := Unmarshal(tt.msg, tt.v)
+		if err != nil && tt.ok {
+			t.Errorf("%s: Unmarshal() unexpected error: %s", tt.desc, err)
+			continue
+		}
+		if err == nil && !tt.ok {
+			t.Errorf("%s: Unmarshal() expected an error", tt.desc)
+			continue
+		}
+		if tt.ok && !reflect.DeepEqual(tt.v, tt.want) {
+			t.Errorf("%s: Unmarshal() = %+v, want = %+v", tt.desc, tt.v, tt.want)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	in := fader{Channel: 7, Level: 0.25, Label: "vox"}
+	msg, err := Marshal("/mixer/fader", &in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := msg.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = NewMessageFromData(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out fader
+	if err = Unmarshal(msg, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("round trip = %+v, want = %+v", out, in)
+	}
+}
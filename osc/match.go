@@ -0,0 +1,352 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+////
+// OSC address pattern matching
+////
+
+// ErrBadPattern is returned for an invalid OSC address pattern.
+var ErrBadPattern = errors.New("invalid address pattern")
+
+// matchAddress reports whether the OSC address `addr` matches the OSC address
+// pattern `pattern`. See CompilePattern for the pattern syntax.
+func matchAddress(pattern, addr string) (bool, error) {
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		return false, err
+	}
+	return p.Match(addr), nil
+}
+
+// tokenKind is the kind of an element of an address pattern part.
+type tokenKind uint8
+
+const (
+	tokenLiteral tokenKind = iota // a literal string
+	tokenAny                      // '?'
+	tokenStar                     // '*'
+	tokenClass                    // '[...]'
+	tokenAlt                      // '{...}'
+)
+
+// patternToken is a single element of an address pattern part.
+type patternToken struct {
+	kind tokenKind
+	// lit is the string of a tokenLiteral.
+	lit string
+	// ranges holds pairs of the first and last character of each range in a
+	// tokenClass. A single character is a range with itself.
+	ranges []rune
+	negate bool
+	// alts holds the strings of a tokenAlt.
+	alts []string
+}
+
+// patternPart is the part of an address pattern between two '/'.
+type patternPart struct {
+	// descendant marks the '//' wildcard, which matches any number of parts.
+	descendant bool
+	tokens     []patternToken
+	// branches is set if the tokens contain a '*' or '{...}'.
+	branches bool
+}
+
+// parsePattern parses the OSC address pattern `pattern` into its parts. A
+// nil slice is returned for the match-all pattern "*".
+func parsePattern(pattern string) ([]patternPart, error) {
+	if pattern == "*" {
+		return nil, nil
+	}
+	if len(pattern) == 0 || pattern[0] != '/' {
+		return nil, fmt.Errorf("%w: %q: must start with '/'", ErrBadPattern, pattern)
+	}
+
+	segs := strings.Split(pattern[1:], "/")
+	parts := make([]patternPart, len(segs))
+	for i, seg := range segs {
+		// An empty part that is followed by another one stems from a '//'.
+		if seg == "" && i < len(segs)-1 {
+			parts[i].descendant = true
+			continue
+		}
+
+		tokens, err := parsePart(seg)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %s", ErrBadPattern, pattern, err)
+		}
+		parts[i].tokens = tokens
+		for _, t := range tokens {
+			if t.kind == tokenStar || t.kind == tokenAlt {
+				parts[i].branches = true
+			}
+		}
+	}
+
+	return parts, nil
+}
+
+// parsePart parses a single part of an address pattern into its tokens.
+func parsePart(s string) ([]patternToken, error) {
+	var tokens []patternToken
+
+	for len(s) > 0 {
+		switch s[0] {
+		case '?':
+			tokens = append(tokens, patternToken{kind: tokenAny})
+			s = s[1:]
+
+		case '*':
+			// Consecutive stars are equivalent to a single one.
+			if len(tokens) == 0 || tokens[len(tokens)-1].kind != tokenStar {
+				tokens = append(tokens, patternToken{kind: tokenStar})
+			}
+			s = s[1:]
+
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return nil, errors.New("unbalanced '['")
+			}
+			t, err := parseClass(s[1:end])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			s = s[end+1:]
+
+		case '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return nil, errors.New("unbalanced '{'")
+			}
+			list := s[1:end]
+			if strings.ContainsAny(list, "{[]") {
+				return nil, fmt.Errorf("invalid character in %q", s[:end+1])
+			}
+			tokens = append(tokens, patternToken{kind: tokenAlt, alts: strings.Split(list, ",")})
+			s = s[end+1:]
+
+		case ']', '}':
+			return nil, fmt.Errorf("unbalanced '%c'", s[0])
+
+		default:
+			end := strings.IndexAny(s, "?*[]{}")
+			if end < 0 {
+				end = len(s)
+			}
+			tokens = append(tokens, patternToken{kind: tokenLiteral, lit: s[:end]})
+			s = s[end:]
+		}
+	}
+
+	return tokens, nil
+}
+
+// parseClass parses the list of characters between '[' and ']'.
+func parseClass(list string) (patternToken, error) {
+	t := patternToken{kind: tokenClass}
+	if strings.HasPrefix(list, "!") {
+		t.negate = true
+		list = list[1:]
+	}
+	if len(list) == 0 {
+		return t, errors.New("empty character list")
+	}
+	if strings.ContainsAny(list, "[{}") {
+		return t, fmt.Errorf("invalid character in \"[%s]\"", list)
+	}
+
+	chars := []rune(list)
+	for i := 0; i < len(chars); i++ {
+		lo, hi := chars[i], chars[i]
+		// A '-' between two characters denotes a range.
+		if i+2 < len(chars) && chars[i+1] == '-' {
+			hi = chars[i+2]
+			i += 2
+			if hi < lo {
+				return t, fmt.Errorf("invalid range %c-%c", lo, hi)
+			}
+		}
+		t.ranges = append(t.ranges, lo, hi)
+	}
+
+	return t, nil
+}
+
+// matches reports whether the character `c` is in the character class.
+func (t *patternToken) matches(c rune) bool {
+	for i := 0; i < len(t.ranges); i += 2 {
+		if t.ranges[i] <= c && c <= t.ranges[i+1] {
+			return !t.negate
+		}
+	}
+	return t.negate
+}
+
+// matchParts reports whether the OSC address `addr` matches the parsed
+// address pattern `parts`.
+func matchParts(parts []patternPart, addr string) bool {
+	if parts == nil {
+		return true
+	}
+	if len(addr) == 0 || addr[0] != '/' {
+		return false
+	}
+
+	for _, p := range parts {
+		if p.descendant {
+			segs := strings.Split(addr[1:], "/")
+			m := matcher{parts: parts, segs: segs, memo: make([]uint8, (len(parts)+1)*(len(segs)+1))}
+			return m.match(0, 0)
+		}
+	}
+
+	// Without a '//', every pattern part matches exactly one address part.
+	rest := addr[1:]
+	for i := range parts {
+		seg := rest
+		j := strings.IndexByte(rest, '/')
+		if j >= 0 {
+			seg, rest = rest[:j], rest[j+1:]
+		}
+		if (j < 0) != (i == len(parts)-1) || !parts[i].match(seg) {
+			return false
+		}
+	}
+	return true
+}
+
+// matcher matches the parts of an address pattern that contains a '//'
+// against the parts of an address. The results for each pair of positions are
+// memorized to keep the matching time polynomial.
+type matcher struct {
+	parts []patternPart
+	segs  []string
+	memo  []uint8
+}
+
+const (
+	memoUnknown uint8 = iota
+	memoTrue
+	memoFalse
+)
+
+// match reports whether the pattern parts from index `p` on match the
+// address parts from index `s` on.
+func (m *matcher) match(p, s int) bool {
+	if p == len(m.parts) {
+		return s == len(m.segs)
+	}
+
+	idx := p*(len(m.segs)+1) + s
+	if r := m.memo[idx]; r != memoUnknown {
+		return r == memoTrue
+	}
+
+	var ok bool
+	switch part := &m.parts[p]; {
+	case part.descendant:
+		ok = m.match(p+1, s) || (s < len(m.segs) && m.match(p, s+1))
+	case s < len(m.segs):
+		ok = part.match(m.segs[s]) && m.match(p+1, s+1)
+	}
+
+	m.memo[idx] = memoFalse
+	if ok {
+		m.memo[idx] = memoTrue
+	}
+	return ok
+}
+
+// match reports whether the address part `s` matches the pattern part.
+func (p *patternPart) match(s string) bool {
+	if len(p.tokens) == 1 && p.tokens[0].kind == tokenLiteral {
+		return p.tokens[0].lit == s
+	}
+
+	m := partMatcher{tokens: p.tokens, s: s}
+	if p.branches {
+		m.memo = make([]uint8, (len(p.tokens)+1)*(len(s)+1))
+	}
+	return m.match(0, 0)
+}
+
+// partMatcher matches the tokens of a pattern part against an address part.
+// If the tokens contain a '*' or '{...}', the results for each pair of
+// positions are memorized to keep the matching time polynomial.
+type partMatcher struct {
+	tokens []patternToken
+	s      string
+	memo   []uint8
+}
+
+// match reports whether the tokens from index `t` on match the string from
+// byte offset `i` on.
+func (m *partMatcher) match(t, i int) bool {
+	for ; t < len(m.tokens); t++ {
+		tok := &m.tokens[t]
+		switch tok.kind {
+		case tokenLiteral:
+			if !strings.HasPrefix(m.s[i:], tok.lit) {
+				return false
+			}
+			i += len(tok.lit)
+
+		case tokenAny, tokenClass:
+			if i == len(m.s) {
+				return false
+			}
+			c, n := utf8.DecodeRuneInString(m.s[i:])
+			if tok.kind == tokenClass && !tok.matches(c) {
+				return false
+			}
+			i += n
+
+		case tokenStar, tokenAlt:
+			return m.branch(t, i)
+		}
+	}
+
+	return i == len(m.s)
+}
+
+// branch matches the '*' or '{...}' token at index `t` and everything that
+// follows it against the string from byte offset `i` on.
+func (m *partMatcher) branch(t, i int) bool {
+	idx := t*(len(m.s)+1) + i
+	if r := m.memo[idx]; r != memoUnknown {
+		return r == memoTrue
+	}
+
+	ok := false
+	tok := &m.tokens[t]
+	if tok.kind == tokenStar {
+		for j := i; !ok; {
+			ok = m.match(t+1, j)
+			if j == len(m.s) {
+				break
+			}
+			_, n := utf8.DecodeRuneInString(m.s[j:])
+			j += n
+		}
+	} else {
+		for _, alt := range tok.alts {
+			if strings.HasPrefix(m.s[i:], alt) && m.match(t+1, i+len(alt)) {
+				ok = true
+				break
+			}
+		}
+	}
+
+	m.memo[idx] = memoFalse
+	if ok {
+		m.memo[idx] = memoTrue
+	}
+	return ok
+}
@@ -0,0 +1,117 @@
+package osc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMatchAddress(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		addr    string
+		want    bool
+	}{
+		{"*", "/a/b", true},
+		{"/", "/", true},
+		{"/foo", "/foo", true},
+		{"/foo", "/foobar", false},
+		{"/foo", "/xfoo", false},
+		{"/foo", "/foo/bar", false},
+		{"/foo/bar", "/foo", false},
+		{"/a.b", "/a.b", true},
+		{"/a.b", "/axb", false},
+		{"/a+", "/aa", false},
+		{"/a+", "/a+", true},
+		{"/^a$", "/^a$", true},
+		{"/a|b", "/a", false},
+		{`/a\d`, `/a\d`, true},
+		{`/a\d`, "/a1", false},
+		{"/a(b)", "/a(b)", true},
+		{"/?", "/a", true},
+		{"/?", "/ab", false},
+		{"/?", "/", false},
+		{"/a/?/c", "/a/b/c", true},
+		{"/*", "/abc", true},
+		{"/*", "/", true},
+		{"/*", "/a/b", false},
+		{"/a*", "/a/b", false},
+		{"/a*c", "/abc", true},
+		{"/a*c", "/abcd", false},
+		{"/*b*", "/abc", true},
+		{"/**", "/abc", true},
+		{"/*/b", "/a/b", true},
+		{"/[abc]", "/b", true},
+		{"/[abc]", "/d", false},
+		{"/[a-c]x", "/bx", true},
+		{"/[a-c]x", "/dx", false},
+		{"/[!a-c]", "/d", true},
+		{"/[!a-c]", "/b", false},
+		{"/[-a]", "/-", true},
+		{"/[a-]", "/-", true},
+		{"/[a-]", "/b", false},
+		{"/a/{foo,bar}", "/a/foo", true},
+		{"/a/{foo,bar}", "/a/bar", true},
+		{"/a/{foo,bar}", "/a/bob", false},
+		{"/a/{foo,bar}", "/a/foobar", false},
+		{"/{a,ab}c", "/abc", true},
+		{"/{,x}y", "/y", true},
+		{"/{a,b}*{c,d}", "/axxd", true},
+		{"//c", "/c", true},
+		{"//c", "/a/b/c", true},
+		{"//c", "/a/b/cd", false},
+		{"/a//c", "/a/c", true},
+		{"/a//c", "/a/b/c", true},
+		{"/a//c", "/b/c", false},
+		{"//b//d", "/a/b/c/d", true},
+		{"//*", "/a/b", true},
+		{"/a/", "/a/", true},
+		{"/a/", "/a", false},
+		{"/ä?", "/äö", true},
+		{"/[ä]", "/ä", true},
+		{"/a", "a", false},
+	} {
+		got, err := matchAddress(tt.pattern, tt.addr)
+		if err != nil {
+			t.Errorf("matchAddress(%q, %q) unexpected error: %s", tt.pattern, tt.addr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("matchAddress(%q, %q) = %t, want = %t", tt.pattern, tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestMatchAddressInvalidPattern(t *testing.T) {
+	for _, pattern := range []string{
+		"",
+		"a/b",
+		"/a[b",
+		"/a[b/c]",
+		"/a]",
+		"/a{b,c",
+		"/a{b,c/d}",
+		"/a}",
+		"/{a{b}}",
+		"/[]",
+		"/[!]",
+		"/[z-a]",
+	} {
+		if _, err := matchAddress(pattern, "/a"); !errors.Is(err, ErrBadPattern) {
+			t.Errorf("matchAddress(%q) error = %v, want = %v", pattern, err, ErrBadPattern)
+		}
+	}
+}
+
+func TestMatchAddressPathological(t *testing.T) {
+	// Both patterns take exponential time with a naive backtracking matcher.
+	pattern := "/" + strings.Repeat("a*", 30) + "b"
+	if ok, _ := matchAddress(pattern, "/"+strings.Repeat("a", 100)); ok {
+		t.Errorf("matchAddress(%q) = true, want = false", pattern)
+	}
+
+	pattern = strings.Repeat("//a", 30) + "/b"
+	if ok, _ := matchAddress(pattern, strings.Repeat("/a", 100)); ok {
+		t.Errorf("matchAddress(%q) = true, want = false", pattern)
+	}
+}
@@ -39,13 +39,11 @@ func (m *Message) Append(args ...interface{}) error {
 }
 
 // Match returns true, if the OSC address pattern of the OSC Message matches the given
-// address. The match is case sensitive!
+// address. The match is case sensitive! An invalid address pattern doesn't
+// match any address.
 func (m *Message) Match(addr string) bool {
-	regexp, err := getRegEx(m.Address)
-	if err != nil {
-		return false
-	}
-	return regexp.MatchString(addr)
+	ok, err := matchAddress(m.Address, addr)
+	return ok && err == nil
 }
 
 // TypeTags returns the type tag string.
@@ -100,6 +98,12 @@ func (m *Message) String() string {
 
 		case Timetag:
 			fmt.Fprintf(strBuf, " %d", arg.TimeTag())
+
+		case Char, RGBA, MIDI, Symbol, Impulse:
+			fmt.Fprintf(strBuf, " %s", arg)
+
+		case []interface{}:
+			fmt.Fprintf(strBuf, " %v", arg)
 		}
 	}
 
@@ -119,18 +123,59 @@ func (m *Message) MarshalBinary() (b []byte, err error) {
 	return append(b, data.Bytes()...), nil
 }
 
+// LightMarshalBinary allows you to marshal a message into a bytes.Buffer to
+// avoid an allocation. Messages of MaxPacketSize bytes or more are rejected.
 func (m *Message) LightMarshalBinary(data *bytes.Buffer) error {
+	return m.marshal(data, MaxPacketSize)
+}
+
+// marshal appends the message to `data`. Messages of `maxSize` bytes or more
+// are rejected.
+func (m *Message) marshal(data *bytes.Buffer, maxSize int) error {
 	b := bufPool.Get().(*bytes.Buffer)
 	defer bufPool.Put(b)
 	b.Reset()
 
 	// Process the type tags and collect all arguments
-	for _, arg := range m.Arguments {
+	if err := writeArguments(m.Arguments, b, maxSize); err != nil {
+		return fmt.Errorf("LightMarshalBinary: %w", err)
+	}
+
+	if b.Len() >= maxSize {
+		return fmt.Errorf("LightMarshalBinary: payload too large: %d", b.Len())
+	}
+
+	start := data.Len()
+
+	writePaddedString(m.Address, data)
+
+	// Write the type tag string to the data buffer
+	typetags, err := m.TypeTags()
+	if err != nil {
+		return err
+	}
+	writePaddedString(typetags, data)
+
+	// Write the payload (OSC arguments) to the data buffer
+	data.Write(b.Bytes())
+
+	if n := data.Len() - start; n >= maxSize {
+		return fmt.Errorf("LightMarshalBinary: packet too large: %d", n)
+	}
+
+	return nil
+}
+
+// writeArguments writes the data of the OSC arguments `args` to `b`. The
+// elements of an array are written in place, one after another. Blobs that
+// don't fit in a packet of `maxSize` bytes are rejected.
+func writeArguments(args []interface{}, b *bytes.Buffer, maxSize int) error {
+	for _, arg := range args {
 		switch t := arg.(type) {
 		default:
-			return fmt.Errorf("LightMarshalBinary: unsupported type: %T", t)
+			return fmt.Errorf("unsupported type: %T", t)
 
-		case bool, nil:
+		case bool, nil, Impulse:
 			continue
 		case int32:
 			buf := make([]byte, bit32Size)
@@ -151,36 +196,30 @@ func (m *Message) LightMarshalBinary(data *bytes.Buffer) error {
 		case string:
 			writePaddedString(t, b)
 		case []byte:
-			if _, err := writeBlob(t, b); err != nil {
+			if _, err := writeBlob(t, b, maxSize); err != nil {
 				return err
 			}
 		case Timetag:
 			buf := make([]byte, bit64Size)
 			binary.BigEndian.PutUint64(buf, uint64(t))
 			b.Write(buf)
+		case Char:
+			buf := make([]byte, bit32Size)
+			binary.BigEndian.PutUint32(buf, uint32(t))
+			b.Write(buf)
+		case RGBA:
+			b.Write([]byte{t.R, t.G, t.B, t.A})
+		case MIDI:
+			b.Write([]byte{t.Port, t.Status, t.Data1, t.Data2})
+		case Symbol:
+			writePaddedString(string(t), b)
+		case []interface{}:
+			if err := writeArguments(t, b, maxSize); err != nil {
+				return err
+			}
 		}
 	}
 
-	if b.Len() >= MaxPacketSize {
-		return fmt.Errorf("LightMarshalBinary: payload too large: %d", b.Len())
-	}
-
-	writePaddedString(m.Address, data)
-
-	// Write the type tag string to the data buffer
-	typetags, err := m.TypeTags()
-	if err != nil {
-		return err
-	}
-	writePaddedString(typetags, data)
-
-	// Write the payload (OSC arguments) to the data buffer
-	data.Write(b.Bytes())
-
-	if data.Len() >= MaxPacketSize {
-		return fmt.Errorf("LightMarshalBinary: packet too large: %d", data.Len())
-	}
-
 	return nil
 }
 
@@ -192,14 +231,21 @@ func NewMessageFromData(data []byte) (msg *Message, err error) {
 	return msg, nil
 }
 
-// UnmarshalBinary implements the encoding.BinaryUnmarshaler
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// enforcing the DefaultLimits. A *DecodeError is returned if data isn't a
+// valid OSC message.
 func (m *Message) UnmarshalBinary(data []byte) error {
-	if data[0] != '/' {
-		return fmt.Errorf("UnmarshalBinary: data not a valid OSC message")
+	return m.unmarshalBinary(data, &DefaultLimits)
+}
+
+// unmarshalBinary parses a message, enforcing the given limits.
+func (m *Message) unmarshalBinary(data []byte, limits *Limits) error {
+	if len(data) == 0 || data[0] != '/' {
+		return &DecodeError{Err: ErrBadAddress}
 	}
 
-	if (len(data) % bit32Size) != 0 {
-		return fmt.Errorf("UnmarshalBinary: data isn't mod 4")
+	if n := len(data) % bit32Size; n != 0 {
+		return &DecodeError{Offset: len(data) - n, Err: ErrBadPadding}
 	}
 
 	b := bufPool.Get().(*bytes.Buffer)
@@ -207,28 +253,29 @@ func (m *Message) UnmarshalBinary(data []byte) error {
 	b.Reset()
 
 	b.Write(data)
+	reader := &packetReader{Buffer: b, size: len(data), limits: limits}
 
 	// First, read the OSC address
 	addr, _, err := readPaddedString(b)
 	if err != nil {
-		return fmt.Errorf("UnmarshalBinary: %w", err)
+		return &DecodeError{Err: decodeCause(err)}
+	}
+	if exceeds(len(addr), limits.MaxAddressLength) {
+		return &DecodeError{Err: ErrLimitExceeded}
 	}
 
 	// Read all arguments
 	m.Address = addr
-	if err = m.readArguments(b); err != nil {
-		return fmt.Errorf("UnmarshalBinary: %w", err)
-	}
-
-	return nil
+	return m.readArguments(reader)
 }
 
 // readArguments from `reader` and add them to the OSC message `msg`.
-func (m *Message) readArguments(reader *bytes.Buffer) error {
+func (m *Message) readArguments(reader *packetReader) error {
 	// Read the type tag string
-	typetags, _, err := readPaddedString(reader)
+	off := reader.offset()
+	typetags, _, err := readPaddedString(reader.Buffer)
 	if err != nil {
-		return fmt.Errorf("readArguments: %w", err)
+		return &DecodeError{Offset: off, Err: decodeCause(err)}
 	}
 
 	if len(typetags) == 0 {
@@ -237,76 +284,158 @@ func (m *Message) readArguments(reader *bytes.Buffer) error {
 
 	// If the typetag doesn't start with ',', it's not valid
 	if typetags[0] != ',' {
-		return fmt.Errorf("unsupported typetag string: %s", typetags)
+		return &DecodeError{Offset: off, TypeTag: typetags[0], Err: ErrBadTypeTag}
 	}
 
-	m.Arguments = make([]interface{}, 0, len(typetags)-1)
-
-	for _, c := range typetags[1:] {
-		if reader.Len() < bit32Size {
-			return fmt.Errorf("readArguments: not enough bits to read")
-		}
-		switch c {
-		default:
-			return fmt.Errorf("unsupported typetag: %c", c)
+	args, rest, err := readArgumentList(typetags[1:], reader, 0)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return &DecodeError{Offset: reader.offset(), TypeTag: ']', Err: ErrBadTypeTag}
+	}
+	m.Arguments = args
 
-		case 'i': // int32
-			m.Arguments = append(m.Arguments, int32(binary.BigEndian.Uint32(reader.Next(bit32Size))))
+	return nil
+}
 
-		case 'h': // int64
-			if reader.Len() < bit64Size {
-				return fmt.Errorf("readArguments: not enough bits to read")
+// readArgumentList reads the arguments for the type tags in `tags` from
+// `reader`. It stops at the end of `tags` or at a ']' that closes the current
+// array and returns the unprocessed rest of `tags`, starting with the ']'.
+// `depth` is the number of enclosing arrays.
+func readArgumentList(tags string, reader *packetReader, depth int) ([]interface{}, string, error) {
+	args := make([]interface{}, 0, len(tags))
+
+	for len(tags) > 0 {
+		c := tags[0]
+		if c != ']' {
+			reader.args++
+			if exceeds(reader.args, reader.limits.MaxArguments) {
+				return nil, "", &DecodeError{Offset: reader.offset(), TypeTag: c, Err: ErrLimitExceeded}
 			}
-			m.Arguments = append(m.Arguments, int64(binary.BigEndian.Uint64(reader.Next(bit64Size))))
+		}
 
-		case 'f': // float32
-			f := binary.BigEndian.Uint32(reader.Next(bit32Size))
-			m.Arguments = append(m.Arguments, *(*float32)(unsafe.Pointer(&f)))
+		switch c {
+		case ']':
+			return args, tags, nil
 
-		case 'd': // float64/double
-			if reader.Len() < bit64Size {
-				return fmt.Errorf("readArguments: not enough bits to read")
+		case '[':
+			if exceeds(depth+1, reader.limits.MaxArrayDepth) {
+				return nil, "", &DecodeError{Offset: reader.offset(), TypeTag: c, Err: ErrLimitExceeded}
 			}
-			f := binary.BigEndian.Uint64(reader.Next(bit64Size))
-			m.Arguments = append(m.Arguments, *(*float64)(unsafe.Pointer(&f)))
-
-		case 's': // string
-			str, err := reader.ReadString(0)
+			arr, rest, err := readArgumentList(tags[1:], reader, depth+1)
 			if err != nil {
-				return err
+				return nil, "", err
 			}
-			if str[0] == 0 {
-				return fmt.Errorf("readArguments: empty string")
+			if len(rest) == 0 {
+				return nil, "", &DecodeError{Offset: reader.offset(), TypeTag: '[', Err: ErrBadTypeTag}
 			}
-			// Remove the padding bytes
-			reader.Next(padBytesNeeded(len(str)))
-			str = str[:len(str)-1]
-
-			m.Arguments = append(m.Arguments, str)
+			args = append(args, arr)
+			tags = rest[1:]
 
-		case 'b': // blob
-			var buf []byte
-			if buf, _, err = readBlob(reader); err != nil {
-				return fmt.Errorf("readArguments: %w", err)
+		default:
+			arg, err := readArgument(c, reader)
+			if err != nil {
+				return nil, "", err
 			}
-			m.Arguments = append(m.Arguments, buf)
+			args = append(args, arg)
+			tags = tags[1:]
+		}
+	}
 
-		case 't': // OSC time tag
-			if reader.Len() < bit64Size {
-				return fmt.Errorf("readArguments: not enough bits to read")
-			}
-			m.Arguments = append(m.Arguments, Timetag(binary.BigEndian.Uint64(reader.Next(bit64Size))))
+	return args, tags, nil
+}
+
+// readArgument reads a single argument with the type tag `c` from `reader`.
+func readArgument(c byte, reader *packetReader) (interface{}, error) {
+	off := reader.offset()
+	n := argumentSize(c)
+	if n < 0 {
+		return nil, &DecodeError{Offset: off, TypeTag: c, Err: ErrBadTypeTag}
+	}
+	if reader.Len() < n {
+		return nil, &DecodeError{Offset: off, TypeTag: c, Err: ErrTruncated}
+	}
+
+	switch c {
+	case 'i': // int32
+		return int32(binary.BigEndian.Uint32(reader.Next(bit32Size))), nil
+
+	case 'h': // int64
+		return int64(binary.BigEndian.Uint64(reader.Next(bit64Size))), nil
 
-		case 'N': // nil
-			m.Arguments = append(m.Arguments, nil)
+	case 'f': // float32
+		f := binary.BigEndian.Uint32(reader.Next(bit32Size))
+		return *(*float32)(unsafe.Pointer(&f)), nil
 
-		case 'T': // true
-			m.Arguments = append(m.Arguments, true)
+	case 'd': // float64/double
+		f := binary.BigEndian.Uint64(reader.Next(bit64Size))
+		return *(*float64)(unsafe.Pointer(&f)), nil
+
+	case 's', 'S': // string, symbol
+		str, _, err := readPaddedString(reader.Buffer)
+		if err != nil {
+			return nil, &DecodeError{Offset: off, TypeTag: c, Err: decodeCause(err)}
+		}
+		if exceeds(len(str), reader.limits.MaxStringLength) {
+			return nil, &DecodeError{Offset: off, TypeTag: c, Err: ErrLimitExceeded}
+		}
+
+		if c == 'S' {
+			return Symbol(str), nil
+		}
+		return str, nil
 
-		case 'F': // false
-			m.Arguments = append(m.Arguments, false)
+	case 'b': // blob
+		if exceeds(int(int32(binary.BigEndian.Uint32(reader.Bytes()))), reader.limits.MaxBlobLength) {
+			return nil, &DecodeError{Offset: off, TypeTag: c, Err: ErrLimitExceeded}
 		}
+		buf, _, err := readBlob(reader.Buffer)
+		if err != nil {
+			return nil, &DecodeError{Offset: off, TypeTag: c, Err: decodeCause(err)}
+		}
+		return buf, nil
+
+	case 't': // OSC time tag
+		return Timetag(binary.BigEndian.Uint64(reader.Next(bit64Size))), nil
+
+	case 'c': // char
+		return Char(binary.BigEndian.Uint32(reader.Next(bit32Size))), nil
+
+	case 'r': // RGBA color
+		v := reader.Next(bit32Size)
+		return RGBA{R: v[0], G: v[1], B: v[2], A: v[3]}, nil
+
+	case 'm': // MIDI message
+		v := reader.Next(bit32Size)
+		return MIDI{Port: v[0], Status: v[1], Data1: v[2], Data2: v[3]}, nil
+
+	case 'I': // impulse
+		return Impulse{}, nil
+
+	case 'T': // true
+		return true, nil
+
+	case 'F': // false
+		return false, nil
+
+	default: // 'N', nil
+		return nil, nil
 	}
+}
 
-	return nil
+// argumentSize returns the minimum number of bytes the argument with the type
+// tag `c` occupies in the argument data, or -1 if `c` isn't a supported type
+// tag.
+func argumentSize(c byte) int {
+	switch c {
+	case 'N', 'I', 'T', 'F':
+		return 0
+	case 'i', 'f', 's', 'S', 'b', 'c', 'r', 'm':
+		return bit32Size
+	case 'h', 'd', 't':
+		return bit64Size
+	default:
+		return -1
+	}
 }
@@ -52,7 +52,16 @@ func TestMessage_TypeTags(t *testing.T) {
 		{"float64", NewMessage("/", float64(4.0)), ",d", true},
 		{"string", NewMessage("/", "5"), ",s", true},
 		{"[]byte", NewMessage("/", []byte{'6'}), ",b", true},
+		{"char", NewMessage("/", Char('a')), ",c", true},
+		{"rgba", NewMessage("/", RGBA{1, 2, 3, 4}), ",r", true},
+		{"midi", NewMessage("/", MIDI{0, 0x90, 60, 127}), ",m", true},
+		{"symbol", NewMessage("/", Symbol("sym")), ",S", true},
+		{"impulse", NewMessage("/", Impulse{}), ",I", true},
 		{"two_args", NewMessage("/", "123", int32(456)), ",si", true},
+		{"array", NewMessage("/", []interface{}{int32(1), "2"}), ",[is]", true},
+		{"empty_array", NewMessage("/", []interface{}{}), ",[]", true},
+		{"nested_array", NewMessage("/", true, []interface{}{int32(1), []interface{}{"2", nil}}, false), ",T[i[sN]]F", true},
+		{"invalid_array_elem", NewMessage("/", []interface{}{789}), "", false},
 		{"invalid_msg", nil, "", false},
 		{"invalid_arg", NewMessage("/foo/bar", 789), "", false},
 	} {
@@ -84,6 +93,8 @@ func TestMessage_String(t *testing.T) {
 		{"addr_only", NewMessage("/foo/bar"), "/foo/bar ,"},
 		{"one_addr", NewMessage("/foo/bar", "123"), "/foo/bar ,s 123"},
 		{"two_args", NewMessage("/foo/bar", "123", int32(456)), "/foo/bar ,si 123 456"},
+		{"extended", NewMessage("/foo/bar", Char('x'), RGBA{255, 0, 16, 128}, MIDI{1, 0x90, 60, 127}, Symbol("sym"), Impulse{}),
+			"/foo/bar ,crmSI x #ff001080 01903c7f sym Impulse"},
 	} {
 		if got, want := tt.msg.String(), tt.str; got != want {
 			t.Errorf("%s: String() = '%s', want = '%s'", tt.desc, got, want)
@@ -150,6 +161,58 @@ func TestOscMessageMatch(t *testing.T) {
 	}
 }
 
+func TestMessage_ExtendedTypesRoundTrip(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		msg  *Message
+	}{
+		{"char", NewMessage("/a", Char('z'))},
+		{"rgba", NewMessage("/a", RGBA{0x12, 0x34, 0x56, 0x78})},
+		{"midi", NewMessage("/a", MIDI{0, 0xb0, 7, 100})},
+		{"symbol", NewMessage("/a", Symbol("abc"))},
+		{"impulse", NewMessage("/a", Impulse{})},
+		{"no_data_last", NewMessage("/a", int32(1), true, nil, Impulse{})},
+		{"mixed", NewMessage("/a", Symbol("s"), Char('c'), "str", RGBA{1, 2, 3, 4}, Impulse{}, MIDI{1, 2, 3, 4}, float32(1.5))},
+		{"array", NewMessage("/a", []interface{}{int32(1), "two", float32(3)})},
+		{"empty_array", NewMessage("/a", []interface{}{}, int32(1))},
+		{"nested_array", NewMessage("/a", "x", []interface{}{[]interface{}{int32(1), []interface{}{}}, []byte{1, 2}}, true)},
+	} {
+		b, err := tt.msg.MarshalBinary()
+		if err != nil {
+			t.Errorf("%s: MarshalBinary() unexpected error: %s", tt.desc, err)
+			continue
+		}
+		got, err := NewMessageFromData(b)
+		if err != nil {
+			t.Errorf("%s: NewMessageFromData() unexpected error: %s", tt.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.msg) {
+			t.Errorf("%s: round trip = %v, want = %v", tt.desc, got, tt.msg)
+		}
+	}
+}
+
+func TestMessage_UnbalancedArray(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		tags string
+	}{
+		{"unterminated", ",[i"},
+		{"unterminated_nested", ",[[i]"},
+		{"unopened", ",i]"},
+	} {
+		b := new(bytes.Buffer)
+		writePaddedString("/a", b)
+		writePaddedString(tt.tags, b)
+		b.Write([]byte{0, 0, 0, 1})
+
+		if _, err := NewMessageFromData(b.Bytes()); err == nil {
+			t.Errorf("%s: NewMessageFromData() expected an error", tt.desc)
+		}
+	}
+}
+
 var result interface{}
 
 func BenchmarkMessageString(b *testing.B) {
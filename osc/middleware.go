@@ -0,0 +1,24 @@
+package osc
+
+// Middleware wraps a Handler with additional behavior, e.g. logging, timing
+// or validating the arguments. The returned Handler usually calls `next`. To
+// pass the context of the message on to `next`, return a ContextHandler that
+// calls `next` with HandleContext.
+type Middleware func(next Handler) Handler
+
+// chain wraps `h` with `middleware`. The first middleware is the outermost
+// one, i.e. it is called first.
+func chain(middleware []Middleware, h Handler) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// wrap returns a copy of the route whose handler is wrapped with the
+// dispatcher middleware `middleware`.
+func (r *route) wrap(middleware []Middleware) *route {
+	c := *r
+	c.handler = chain(middleware, r.base)
+	return &c
+}
@@ -0,0 +1,96 @@
+package osc
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tracer returns a middleware that appends its name to `calls` before it
+// calls the next handler.
+func tracer(name string, calls *[]string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(msg *Message) {
+			*calls = append(*calls, name)
+			next.HandleMessage(msg)
+		})
+	}
+}
+
+func TestDispatcherMiddleware(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var calls []string
+		handler := func(name string) HandlerFunc {
+			return func(msg *Message) { calls = append(calls, name) }
+		}
+		dispatch := func(addr string) string {
+			calls = nil
+			d.Dispatch(NewMessage(addr))
+			return strings.Join(calls, " ")
+		}
+
+		d.AddMsgHandler("/a", handler("a"), tracer("r1", &calls), tracer("r2", &calls))
+		if got, want := dispatch("/a"), "r1 r2 a"; got != want {
+			t.Errorf("%s: calls = %q, want = %q", td.name, got, want)
+		}
+
+		// Use wraps the handlers that were added before and after it.
+		d.Use(tracer("g1", &calls), tracer("g2", &calls))
+		d.AddMsgHandler("/b", handler("b"))
+		d.AddMsgHandler("*", handler("*"))
+		if got, want := dispatch("/a"), "g1 g2 r1 r2 a g1 g2 *"; got != want {
+			t.Errorf("%s: calls = %q, want = %q", td.name, got, want)
+		}
+		if got, want := dispatch("/b"), "g1 g2 b g1 g2 *"; got != want {
+			t.Errorf("%s: calls = %q, want = %q", td.name, got, want)
+		}
+
+		d.Use(tracer("g3", &calls))
+		d.ReplaceMsgHandler("/b", handler("b2"), tracer("r3", &calls))
+		if got, want := dispatch("/b"), "g1 g2 g3 r3 b2 g1 g2 g3 *"; got != want {
+			t.Errorf("%s: calls = %q, want = %q", td.name, got, want)
+		}
+	}
+}
+
+func TestDispatcherMiddlewareSubscription(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		sub, err := d.Subscribe("/a", func(msg *Message) {})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Adding middleware doesn't invalidate the subscription.
+		d.Use(func(next Handler) Handler { return next })
+		if !sub.Unsubscribe() {
+			t.Errorf("%s: Unsubscribe() = false, want = true", td.name)
+		}
+	}
+}
+
+func TestDispatcherMiddlewareContext(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var got Timetag
+		d.Use(func(next Handler) Handler {
+			return ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+				HandleContext(ctx, next, msg)
+			})
+		})
+		d.AddHandler("/a", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+			if info, ok := MessageInfoFromContext(ctx); ok {
+				got = info.Timetag
+			}
+		}))
+
+		b := NewBundle(time.Now())
+		b.Append(NewMessage("/a"))
+		dispatchElements(context.Background(), d.(messageDispatcher), b)
+		if got != b.Timetag {
+			t.Errorf("%s: Timetag = %d, want = %d", td.name, got, b.Timetag)
+		}
+	}
+}
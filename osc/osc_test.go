@@ -1,5 +1,10 @@
 package osc
 
+import (
+	"net"
+	"time"
+)
+
 const zero = string(byte(0))
 
 // nulls returns a string of `i` nulls.
@@ -19,3 +24,19 @@ func makePacket(addr string, args []string) Packet {
 	}
 	return msg
 }
+
+// dummyConn is a net.PacketConn that returns the same packet on every read.
+type dummyConn struct {
+	m []byte
+}
+
+func (d *dummyConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	return copy(b, d.m), &net.UDPAddr{}, nil
+}
+
+func (d *dummyConn) WriteTo(b []byte, _ net.Addr) (int, error) { return len(b), nil }
+func (d *dummyConn) Close() error                              { return nil }
+func (d *dummyConn) LocalAddr() net.Addr                       { return &net.UDPAddr{} }
+func (d *dummyConn) SetDeadline(time.Time) error               { return nil }
+func (d *dummyConn) SetReadDeadline(time.Time) error           { return nil }
+func (d *dummyConn) SetWriteDeadline(time.Time) error          { return nil }
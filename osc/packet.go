@@ -1,9 +1,6 @@
 package osc
 
-import (
-	"encoding"
-	"fmt"
-)
+import "encoding"
 
 // Packet is the interface for Message and Bundle.
 type Packet interface {
@@ -11,18 +8,38 @@ type Packet interface {
 	encoding.BinaryUnmarshaler
 }
 
-// ReadPacket parses an OSC packet.
+// ReadPacket parses an OSC packet, enforcing the DefaultLimits. A
+// *DecodeError is returned if data isn't a valid OSC message or bundle.
 func ReadPacket(data []byte) (Packet, error) {
+	return readPacket(data, &DefaultLimits, 0)
+}
+
+// ReadPacketWithLimits parses an OSC packet like ReadPacket, but enforces the
+// given limits. If `limits` is nil, the DefaultLimits are enforced.
+func ReadPacketWithLimits(data []byte, limits *Limits) (Packet, error) {
+	return readPacket(data, limits.orDefault(), 0)
+}
+
+// readPacket parses an OSC packet that is nested in `depth` bundles.
+func readPacket(data []byte, limits *Limits, depth int) (Packet, error) {
 	if !(len(data) > 0) {
-		return nil, fmt.Errorf("ReadPacket: invalid packet")
+		return nil, &DecodeError{Err: ErrTruncated}
 	}
 
 	switch data[0] {
 	case '/': // An OSC Message starts with a '/'
-		return NewMessageFromData(data)
+		msg := &Message{}
+		if err := msg.unmarshalBinary(data, limits); err != nil {
+			return nil, err
+		}
+		return msg, nil
 	case '#': // An OSC bundle starts with a '#'
-		return NewBundleFromData(data)
+		b := &Bundle{}
+		if err := b.unmarshalBinary(data, limits, depth+1); err != nil {
+			return nil, err
+		}
+		return b, nil
 	default:
-		return nil, fmt.Errorf("ReadPacket: invalid packet")
+		return nil, &DecodeError{Err: ErrBadAddress}
 	}
 }
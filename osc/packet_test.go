@@ -1,8 +1,10 @@
 package osc
 
 import (
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestReadPacket(t *testing.T) {
@@ -53,6 +55,106 @@ func TestReadPacket(t *testing.T) {
 	}
 }
 
+func TestReadPacketDecodeError(t *testing.T) {
+	for _, tt := range []struct {
+		desc   string
+		msg    string
+		err    error
+		offset int
+		tag    byte
+	}{
+		{"empty", "", ErrTruncated, 0, 0},
+		{"bad_start", "abcd", ErrBadAddress, 0, 0},
+		{"not_mod_4", "/a" + nulls(2) + ",i", ErrBadPadding, 4, 0},
+		{"unterminated_address", "/abc", ErrTruncated, 0, 0},
+		{"bad_address_padding", "/a" + zero + "x" + "," + nulls(3), ErrBadPadding, 0, 0},
+		{"missing_typetags", "/a" + nulls(2), ErrTruncated, 4, 0},
+		{"bad_typetags", "/a" + nulls(2) + "i" + nulls(3), ErrBadTypeTag, 4, 'i'},
+		{"unknown_tag", "/a" + nulls(2) + ",x" + nulls(2) + nulls(4), ErrBadTypeTag, 8, 'x'},
+		{"short_int", "/a" + nulls(2) + ",ii" + zero + nulls(4), ErrTruncated, 12, 'i'},
+		{"short_int64", "/a" + nulls(2) + ",h" + nulls(2) + nulls(4), ErrTruncated, 8, 'h'},
+		{"unterminated_string", "/a" + nulls(2) + ",s" + nulls(2) + "abcd", ErrTruncated, 8, 's'},
+		{"bad_string_padding", "/a" + nulls(2) + ",s" + nulls(2) + "a" + zero + "b" + zero, ErrBadPadding, 8, 's'},
+		{"short_blob_size", "/a" + nulls(2) + ",b" + nulls(2), ErrTruncated, 8, 'b'},
+		{"negative_blob", "/a" + nulls(2) + ",b" + nulls(2) + "\xff\xff\xff\xff", ErrBadLength, 8, 'b'},
+		{"short_blob", "/a" + nulls(2) + ",b" + nulls(2) + nulls(3) + "\x08" + nulls(4), ErrTruncated, 8, 'b'},
+		{"bad_blob_padding", "/a" + nulls(2) + ",b" + nulls(2) + nulls(3) + "\x01" + "ab" + nulls(2), ErrBadPadding, 8, 'b'},
+		{"unterminated_array", "/a" + nulls(2) + ",[i" + zero + nulls(4), ErrBadTypeTag, 12, '['},
+		{"unopened_array", "/a" + nulls(2) + ",]" + nulls(2), ErrBadTypeTag, 8, ']'},
+		{"short_bundle", "#bundle" + zero, ErrTruncated, 0, 0},
+		{"bad_bundle_tag", "#bundlx" + zero + nulls(8), ErrBadBundle, 0, 0},
+		{"short_element", "#bundle" + zero + nulls(8) + nulls(3) + "\x08" + nulls(4), ErrTruncated, 16, 0},
+		{"negative_element", "#bundle" + zero + nulls(8) + "\xff\xff\xff\xff", ErrBadLength, 16, 0},
+		{"nested_error", "#bundle" + zero + nulls(8) + nulls(3) + "\x08" + "/a" + nulls(2) + ",i" + nulls(2),
+			ErrTruncated, 28, 'i'},
+	} {
+		_, err := ReadPacket([]byte(tt.msg))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: ReadPacket() error = %v, want = %v", tt.desc, err, tt.err)
+			continue
+		}
+		var de *DecodeError
+		if !errors.As(err, &de) {
+			t.Errorf("%s: ReadPacket() error = %T, want = *DecodeError", tt.desc, err)
+			continue
+		}
+		if de.Offset != tt.offset || de.TypeTag != tt.tag {
+			t.Errorf("%s: ReadPacket() offset, tag = %d, %q, want = %d, %q", tt.desc, de.Offset, de.TypeTag, tt.offset, tt.tag)
+		}
+	}
+}
+
+func TestReadPacketEmptyElements(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		pkt  Packet
+	}{
+		{"empty_string", NewMessage("/a", "", int32(1))},
+		{"empty_blob", NewMessage("/a", []byte{}, int32(1))},
+		{"empty_bundle", &Bundle{Timetag: 1}},
+	} {
+		b, err := tt.pkt.MarshalBinary()
+		if err != nil {
+			t.Errorf("%s: MarshalBinary() unexpected error: %s", tt.desc, err)
+			continue
+		}
+		got, err := ReadPacket(b)
+		if err != nil {
+			t.Errorf("%s: ReadPacket() unexpected error: %s", tt.desc, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.pkt) {
+			t.Errorf("%s: ReadPacket() = %v, want = %v", tt.desc, got, tt.pkt)
+		}
+	}
+}
+
+func TestReadPacketMalformed(t *testing.T) {
+	inner := NewBundle(time.Unix(0, 0))
+	inner.Append(NewMessage("/c", []byte{1, 2, 3, 4, 5}, Timetag(1)))
+	bundle := NewBundle(time.Unix(0, 0))
+	bundle.Append(NewMessage("/a/b", int32(1), "str", []interface{}{float64(1), RGBA{}}))
+	bundle.Append(inner)
+
+	for _, p := range []Packet{temp, bundle} {
+		data, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Neither truncated nor corrupted packets may panic.
+		for i := range data {
+			ReadPacket(data[:i])
+
+			for _, c := range []byte{0, 1, 0x7f, 0x80, 0xff, '[', ']', ','} {
+				corrupt := append([]byte(nil), data...)
+				corrupt[i] = c
+				ReadPacket(corrupt)
+			}
+		}
+	}
+}
+
 var temp = &Message{Address: "/composition/layers/1/clips/1/transport/position", Arguments: []interface{}{0.123456789, "hello world"}}
 var msg, _ = temp.MarshalBinary()
 
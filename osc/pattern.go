@@ -0,0 +1,120 @@
+package osc
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Pattern is a compiled OSC address pattern. A Pattern is safe for concurrent
+// use by multiple goroutines.
+type Pattern struct {
+	str   string
+	parts []patternPart
+}
+
+// CompilePattern parses an OSC address pattern and returns a Pattern that can
+// be matched against addresses. An error wrapping ErrBadPattern is returned
+// if the pattern is invalid.
+//
+// The pattern follows the OSC 1.0 rules, applied to each part of the address
+// between two '/' separately:
+//
+//	'?'       matches any single character
+//	'*'       matches any sequence of zero or more characters
+//	'[abc]'   matches any character in the list
+//	'[a-z]'   matches any character in the range; a '-' at the start or
+//	          the end of the list matches itself
+//	'[!a-z]'  matches any character that isn't in the list
+//	'{a,bc}'  matches any of the comma separated strings
+//
+// None of them match a '/'. In addition, the OSC 1.1 path-traversing wildcard
+// '//' matches zero or more complete parts of the address, e.g. "//level"
+// matches "/level" and "/mixer/1/level". As a special case, the pattern "*"
+// matches every address.
+func CompilePattern(addr string) (*Pattern, error) {
+	parts, err := parsePattern(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Pattern{str: addr, parts: parts}, nil
+}
+
+// MustCompilePattern is like CompilePattern but panics if the pattern is
+// invalid.
+func MustCompilePattern(addr string) *Pattern {
+	p, err := CompilePattern(addr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Match reports whether the OSC address `addr` matches the pattern.
+func (p *Pattern) Match(addr string) bool {
+	return matchParts(p.parts, addr)
+}
+
+// MatchMessage reports whether the address of the OSC message `msg` matches
+// the pattern.
+func (p *Pattern) MatchMessage(msg *Message) bool {
+	return msg != nil && p.Match(msg.Address)
+}
+
+// String returns the source text of the pattern.
+func (p *Pattern) String() string {
+	return p.str
+}
+
+// defaultPatternCacheSize is the number of patterns a dispatcher caches.
+const defaultPatternCacheSize = 1024
+
+// patternCache is a least recently used cache of compiled address patterns.
+// It is safe for concurrent use.
+type patternCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// patternCacheEntry is the result of compiling an address pattern.
+type patternCacheEntry struct {
+	addr    string
+	pattern *Pattern
+	err     error
+}
+
+// newPatternCache returns a cache that holds up to `size` patterns.
+func newPatternCache(size int) *patternCache {
+	return &patternCache{size: size, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get returns the compiled pattern for `addr`, compiling it if it isn't
+// cached. Invalid patterns are cached as well.
+func (c *patternCache) get(addr string) (*Pattern, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[addr]; ok {
+		c.ll.MoveToFront(e)
+		entry := e.Value.(*patternCacheEntry)
+		return entry.pattern, entry.err
+	}
+
+	p, err := CompilePattern(addr)
+	c.items[addr] = c.ll.PushFront(&patternCacheEntry{addr: addr, pattern: p, err: err})
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*patternCacheEntry).addr)
+	}
+
+	return p, err
+}
+
+// len returns the number of cached patterns.
+func (c *patternCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
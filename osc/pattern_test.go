@@ -0,0 +1,108 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	p, err := CompilePattern("/mixer/{1,2}/fader*")
+	if err != nil {
+		t.Fatalf("CompilePattern() unexpected error: %s", err)
+	}
+	if got, want := p.String(), "/mixer/{1,2}/fader*"; got != want {
+		t.Errorf("String() = %q, want = %q", got, want)
+	}
+
+	for addr, want := range map[string]bool{
+		"/mixer/1/fader":  true,
+		"/mixer/2/fader3": true,
+		"/mixer/3/fader":  false,
+		"/mixer/1/mute":   false,
+	} {
+		if got := p.Match(addr); got != want {
+			t.Errorf("Match(%q) = %t, want = %t", addr, got, want)
+		}
+		if got := p.MatchMessage(NewMessage(addr)); got != want {
+			t.Errorf("MatchMessage(%q) = %t, want = %t", addr, got, want)
+		}
+	}
+	if p.MatchMessage(nil) {
+		t.Error("MatchMessage(nil) = true, want = false")
+	}
+
+	if _, err = CompilePattern("/mixer/{1,2"); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("CompilePattern() error = %v, want = %v", err, ErrBadPattern)
+	}
+}
+
+func TestMustCompilePattern(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustCompilePattern() expected a panic")
+		}
+	}()
+	MustCompilePattern("/a[")
+}
+
+func TestPatternConcurrentUse(t *testing.T) {
+	p := MustCompilePattern("//a*/{b,c}")
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if !p.Match("/x/ab/c") || p.Match("/x/ab/d") {
+					t.Error("unexpected match result")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPatternCache(t *testing.T) {
+	c := newPatternCache(2)
+
+	p1, err := c.get("/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := c.get("/a"); p != p1 {
+		t.Error("get() didn't return the cached pattern")
+	}
+	if _, err = c.get("/b["); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("get() error = %v, want = %v", err, ErrBadPattern)
+	}
+
+	// "/b[" is the least recently used pattern now and gets evicted.
+	c.get("/a")
+	c.get("/c")
+	if got := c.len(); got != 2 {
+		t.Errorf("len() = %d, want = 2", got)
+	}
+	if _, ok := c.items["/b["]; ok {
+		t.Error("least recently used pattern wasn't evicted")
+	}
+	if p, _ := c.get("/a"); p != p1 {
+		t.Error("recently used pattern was evicted")
+	}
+}
+
+func BenchmarkDispatch(b *testing.B) {
+	d := NewStandardDispatcher()
+	for i := 0; i < 100; i++ {
+		d.AddMsgHandler(fmt.Sprintf("/mixer/%d/fader", i), func(msg *Message) {})
+	}
+	msg := NewMessage("/mixer/{1,2,3}/fader", float32(0.5))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		d.Dispatch(msg)
+	}
+}
@@ -0,0 +1,206 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+////
+// Dispatch modes
+////
+
+// DispatchMode decides how a Server hands the received packets to its
+// Dispatcher.
+type DispatchMode int
+
+const (
+	// DispatchConcurrent dispatches every packet in a goroutine of its own.
+	// The order of the packets isn't kept. This is the default.
+	DispatchConcurrent DispatchMode = iota
+	// DispatchSequential dispatches the packets one after the other in the
+	// order in which they were received. Bundles that are due are dispatched
+	// in order as well, bundles with a future time tag when it has come.
+	DispatchSequential
+	// DispatchOrdered dispatches the packets with a pool of workers. The
+	// packets with the same address are dispatched by the same worker, in
+	// the order in which they were received. A bundle goes to the worker of
+	// the address of its first message, due bundles are dispatched in order
+	// like with DispatchSequential. The messages of a bundle with other
+	// addresses are therefore not kept in order with the messages of their
+	// own address; use DispatchSequential for such bundles.
+	DispatchOrdered
+	// DispatchBounded dispatches the packets with a pool of workers that
+	// share one queue. The order of the packets isn't kept.
+	DispatchBounded
+)
+
+// OverflowPolicy decides what a Server does with a received packet if the
+// queue of its worker is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the queue, which stops
+	// reading packets in the meantime. This is the default.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest drops the oldest packet of the queue to make room.
+	OverflowDropOldest
+	// OverflowDropNewest drops the received packet.
+	OverflowDropNewest
+)
+
+// ErrQueueFull is reported to Server.ErrorHandler for the packets that are
+// dropped because the queue of a worker is full.
+var ErrQueueFull = errors.New("dispatch queue full")
+
+// defaultQueueSize is the size of the queues if Server.QueueSize isn't set.
+const defaultQueueSize = 256
+
+// queuedPacket is a received packet that waits for a worker.
+type queuedPacket struct {
+	ctx    context.Context
+	packet Packet
+}
+
+// packetQueue dispatches the packets received by a Server with a fixed
+// number of workers.
+type packetQueue struct {
+	s *Server
+	// queues holds one queue per worker, or a single queue that is shared
+	// by all workers.
+	queues []chan queuedPacket
+	// inline is set if the bundles that are due are dispatched by the
+	// workers, to keep them in order.
+	inline bool
+	once   sync.Once
+}
+
+// newPacketQueue starts the workers for the DispatchMode of `s`. It returns
+// nil for DispatchConcurrent.
+func newPacketQueue(s *Server) *packetQueue {
+	size := s.QueueSize
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	workers := s.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	q := &packetQueue{s: s}
+	switch s.DispatchMode {
+	case DispatchSequential:
+		q.inline = true
+		q.queues = []chan queuedPacket{make(chan queuedPacket, size)}
+		go q.work(q.queues[0])
+	case DispatchOrdered:
+		q.inline = true
+		q.queues = make([]chan queuedPacket, workers)
+		for i := range q.queues {
+			q.queues[i] = make(chan queuedPacket, size)
+			go q.work(q.queues[i])
+		}
+	case DispatchBounded:
+		q.queues = []chan queuedPacket{make(chan queuedPacket, size)}
+		for i := 0; i < workers; i++ {
+			go q.work(q.queues[0])
+		}
+	default:
+		return nil
+	}
+	return q
+}
+
+// work dispatches the packets of `queue` until it is closed.
+func (q *packetQueue) work(queue chan queuedPacket) {
+	for qp := range queue {
+		q.s.dispatch(qp.ctx, qp.packet)
+	}
+}
+
+// push adds `p` to the queue of its worker, applying the OverflowPolicy of
+// the Server if the queue is full.
+func (q *packetQueue) push(ctx context.Context, p Packet) {
+	queue := q.queues[0]
+	if len(q.queues) > 1 {
+		queue = q.queues[hashAddress(orderAddress(p))%uint32(len(q.queues))]
+	}
+	if q.inline {
+		ctx = withInlineBundles(ctx)
+	}
+	qp := queuedPacket{ctx: ctx, packet: p}
+
+	switch q.s.Overflow {
+	case OverflowDropNewest:
+		select {
+		case queue <- qp:
+		default:
+			q.drop(qp)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case queue <- qp:
+				return
+			default:
+			}
+			select {
+			case old := <-queue:
+				q.drop(old)
+			default:
+			}
+		}
+	default:
+		queue <- qp
+	}
+}
+
+// drop reports a packet that was dropped because its queue was full.
+func (q *packetQueue) drop(qp queuedPacket) {
+	defer q.s.addActive(-1)
+	q.s.reportError(fmt.Errorf("%w: packet %s dropped", ErrQueueFull, packetAddress(qp.packet)))
+}
+
+// close stops the workers once they have dispatched the queued packets.
+func (q *packetQueue) close() {
+	q.once.Do(func() {
+		for _, queue := range q.queues {
+			close(queue)
+		}
+	})
+}
+
+// packetAddress returns the address of a message, or "#bundle".
+func packetAddress(p Packet) string {
+	if msg, ok := p.(*Message); ok {
+		return msg.Address
+	}
+	return bundleTagString
+}
+
+// orderAddress returns the address by which the order of `p` is kept: the
+// address of a message, or of the first message of a bundle.
+func orderAddress(p Packet) string {
+	for {
+		b, ok := p.(*Bundle)
+		if !ok {
+			return packetAddress(p)
+		}
+		if len(b.Elements) == 0 {
+			return bundleTagString
+		}
+		p = b.Elements[0]
+	}
+}
+
+// hashAddress returns the 32-bit FNV-1a hash of `addr`.
+func hashAddress(addr string) uint32 {
+	h := uint32(2166136261)
+	for i := 0; i < len(addr); i++ {
+		h ^= uint32(addr[i])
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,240 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServerDispatchOrder(t *testing.T) {
+	for _, mode := range []DispatchMode{DispatchSequential, DispatchOrdered} {
+		const n = 50
+		var mu sync.Mutex
+		got := map[string][]int32{}
+		var wg sync.WaitGroup
+		wg.Add(2 * n)
+		d := NewStandardDispatcher()
+		d.AddMsgHandler("*", func(msg *Message) {
+			mu.Lock()
+			got[msg.Address] = append(got[msg.Address], msg.Arguments[0].(int32))
+			mu.Unlock()
+			wg.Done()
+		})
+		conn := serveTest(t, &Server{Dispatcher: d, DispatchMode: mode, Workers: 4})
+
+		for i := int32(0); i < n; i++ {
+			sendTest(t, conn, NewMessage("/a", i), NewMessage("/b", i))
+		}
+		waitTest(t, &wg)
+
+		mu.Lock()
+		for addr, values := range got {
+			for i, v := range values {
+				if v != int32(i) {
+					t.Errorf("mode %d: %s got %v, want in order", mode, addr, values)
+					break
+				}
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+func TestServerDispatchOrderBundles(t *testing.T) {
+	for _, mode := range []DispatchMode{DispatchSequential, DispatchOrdered} {
+		const n = 60
+		var mu sync.Mutex
+		got := map[string][]int32{}
+		var wg sync.WaitGroup
+		wg.Add(n + n/4)
+		d := NewStandardDispatcher()
+		d.AddMsgHandler("*", func(msg *Message) {
+			mu.Lock()
+			got[msg.Address] = append(got[msg.Address], msg.Arguments[0].(int32))
+			mu.Unlock()
+			wg.Done()
+		})
+		conn := serveTest(t, &Server{Dispatcher: d, DispatchMode: mode, Workers: 4})
+
+		// Messages, immediate bundles, late bundles and bundles whose first
+		// address differs take turns.
+		for i := int32(0); i < n; i++ {
+			var p Packet = NewMessage("/a", i)
+			switch i % 4 {
+			case 1:
+				p = &Bundle{Timetag: 1, Elements: []Packet{p}}
+			case 2:
+				p = &Bundle{Timetag: NewTimetagFromTime(time.Now().Add(-time.Second)), Elements: []Packet{p}}
+			case 3:
+				p = &Bundle{Timetag: 1, Elements: []Packet{NewMessage("/b", i), p}}
+			}
+			sendTest(t, conn, p)
+		}
+		waitTest(t, &wg)
+
+		mu.Lock()
+		// With DispatchOrdered, the bundles are only kept in order with the
+		// messages of their first address.
+		var want, values []int32
+		for i := int32(0); i < n; i++ {
+			if mode == DispatchSequential || i%4 != 3 {
+				want = append(want, i)
+			}
+		}
+		for _, v := range got["/a"] {
+			if mode == DispatchSequential || v%4 != 3 {
+				values = append(values, v)
+			}
+		}
+		if fmt.Sprint(values) != fmt.Sprint(want) {
+			t.Errorf("mode %d: /a got %v, want in order", mode, got["/a"])
+		}
+		if len(got["/a"]) != n || len(got["/b"]) != n/4 {
+			t.Errorf("mode %d: got %d /a and %d /b, want = %d and %d", mode, len(got["/a"]), len(got["/b"]), n, n/4)
+		}
+		mu.Unlock()
+	}
+}
+
+func TestOrderAddress(t *testing.T) {
+	for _, tt := range []struct {
+		p    Packet
+		want string
+	}{
+		{NewMessage("/a"), "/a"},
+		{&Bundle{Elements: []Packet{NewMessage("/b"), NewMessage("/c")}}, "/b"},
+		{&Bundle{Elements: []Packet{&Bundle{Elements: []Packet{NewMessage("/d")}}}}, "/d"},
+		{&Bundle{}, "#bundle"},
+	} {
+		if got := orderAddress(tt.p); got != tt.want {
+			t.Errorf("orderAddress(%v) = %q, want = %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestServerDispatchBounded(t *testing.T) {
+	const n, workers = 20, 2
+	var mu sync.Mutex
+	running, max := 0, 0
+	var wg sync.WaitGroup
+	wg.Add(n)
+	d := NewStandardDispatcher()
+	d.AddMsgHandler("*", func(msg *Message) {
+		mu.Lock()
+		running++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		wg.Done()
+	})
+	conn := serveTest(t, &Server{Dispatcher: d, DispatchMode: DispatchBounded, Workers: workers})
+
+	for i := 0; i < n; i++ {
+		sendTest(t, conn, NewMessage(fmt.Sprintf("/%d", i)))
+	}
+	waitTest(t, &wg)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if max > workers {
+		t.Errorf("%d handlers ran at the same time, want <= %d", max, workers)
+	}
+}
+
+func TestServerOverflow(t *testing.T) {
+	for _, tt := range []struct {
+		desc     string
+		overflow OverflowPolicy
+		want     string
+	}{
+		{"drop newest", OverflowDropNewest, "/1/2"},
+		{"drop oldest", OverflowDropOldest, "/1/3"},
+	} {
+		var mu sync.Mutex
+		var calls []string
+		started, release := make(chan struct{}, 1), make(chan struct{})
+		d := NewStandardDispatcher()
+		d.AddMsgHandler("*", func(msg *Message) {
+			started <- struct{}{}
+			<-release
+			mu.Lock()
+			calls = append(calls, msg.Address)
+			mu.Unlock()
+		})
+		var reported []error
+		s := &Server{
+			Dispatcher:   d,
+			DispatchMode: DispatchSequential,
+			QueueSize:    1,
+			Overflow:     tt.overflow,
+			ErrorHandler: func(err error) { reported = append(reported, err) },
+		}
+
+		q := newPacketQueue(s)
+		push := func(addr string) {
+			s.addActive(1)
+			q.push(context.Background(), NewMessage(addr))
+		}
+		push("/1")
+		<-started
+		push("/2")
+		push("/3")
+		close(release)
+		q.close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := s.Shutdown(ctx); err != nil {
+			t.Fatalf("%s: Shutdown() unexpected error: %s", tt.desc, err)
+		}
+		cancel()
+
+		mu.Lock()
+		if got := strings.Join(calls, ""); got != tt.want {
+			t.Errorf("%s: dispatched %s, want = %s", tt.desc, got, tt.want)
+		}
+		mu.Unlock()
+		if len(reported) != 1 || !errors.Is(reported[0], ErrQueueFull) {
+			t.Errorf("%s: reported %v, want one %v", tt.desc, reported, ErrQueueFull)
+		}
+	}
+}
+
+func TestHashAddress(t *testing.T) {
+	// Known FNV-1a values.
+	for _, tt := range []struct {
+		s    string
+		want uint32
+	}{
+		{"", 0x811c9dc5},
+		{"a", 0xe40c292c},
+		{"foobar", 0xbf9cf968},
+	} {
+		if got := hashAddress(tt.s); got != tt.want {
+			t.Errorf("hashAddress(%q) = %#x, want = %#x", tt.s, got, tt.want)
+		}
+	}
+}
+
+// waitTest waits for `wg` or fails the test after a timeout.
+func waitTest(t *testing.T, wg *sync.WaitGroup) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the handlers")
+	}
+}
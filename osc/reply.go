@@ -0,0 +1,62 @@
+package osc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+)
+
+// ErrNoSender is returned by Reply if there is no sender to reply to, i.e.
+// the message wasn't received by a Server.
+var ErrNoSender = errors.New("no sender to reply to")
+
+// Responder sends packets back to the sender of a message.
+type Responder interface {
+	Reply(packet Packet) error
+}
+
+// Verify that MessageInfo implements the Responder interface.
+var _ Responder = (*MessageInfo)(nil)
+
+// Reply sends `packet` to the sender of the message. It is sent over the
+// connection on which the message was received, so it comes from the address
+// the sender sent the message to. Implements the Responder interface.
+func (i *MessageInfo) Reply(packet Packet) error {
+	if i != nil && i.Stream != nil {
+		return i.Stream.Send(packet)
+	}
+	if i == nil || i.Conn == nil || i.RemoteAddr == nil {
+		return ErrNoSender
+	}
+	return writePacketTo(i.Conn, packet, i.RemoteAddr)
+}
+
+// Reply sends `packet` to the sender of the message that is handled with
+// `ctx`. See MessageInfo.Reply.
+func Reply(ctx context.Context, packet Packet) error {
+	info, _ := MessageInfoFromContext(ctx)
+	return info.Reply(packet)
+}
+
+// writePacketTo writes `packet` to `addr` over `c`.
+func writePacketTo(c net.PacketConn, packet Packet, addr net.Addr) error {
+	var data []byte
+	if lm, ok := packet.(lightMarshaler); ok {
+		b := bufPool.Get().(*bytes.Buffer)
+		defer bufPool.Put(b)
+		b.Reset()
+		if err := lm.LightMarshalBinary(b); err != nil {
+			return err
+		}
+		data = b.Bytes()
+	} else {
+		var err error
+		if data, err = packet.MarshalBinary(); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.WriteTo(data, addr)
+	return err
+}
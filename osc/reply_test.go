@@ -0,0 +1,55 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestReply(t *testing.T) {
+	d := NewStandardDispatcher()
+	d.AddHandler("/ping", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+		if err := Reply(ctx, NewMessage("/pong", msg.Arguments...)); err != nil {
+			t.Errorf("Reply() unexpected error: %s", err)
+		}
+	}))
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go (&Server{Dispatcher: d}).Serve(server)
+
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err = writePacketTo(c, NewMessage("/ping", int32(1)), server.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The reply comes from the port the request was sent to.
+	s := &Server{ReadTimeout: 5 * time.Second}
+	p, addr, err := s.ReceivePacketFrom(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := addr.String(), server.LocalAddr().String(); got != want {
+		t.Errorf("reply sent from %s, want = %s", got, want)
+	}
+	if got, want := p.(*Message).String(), "/pong ,i 1"; got != want {
+		t.Errorf("reply = %s, want = %s", got, want)
+	}
+}
+
+func TestReplyWithoutSender(t *testing.T) {
+	if err := Reply(context.Background(), NewMessage("/pong")); !errors.Is(err, ErrNoSender) {
+		t.Errorf("Reply() error = %v, want = %v", err, ErrNoSender)
+	}
+	if err := (&MessageInfo{}).Reply(NewMessage("/pong")); !errors.Is(err, ErrNoSender) {
+		t.Errorf("Reply() error = %v, want = %v", err, ErrNoSender)
+	}
+}
@@ -0,0 +1,225 @@
+package osc
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+////
+// Bundle scheduler
+////
+
+// LatePolicy decides what a dispatcher does with a bundle whose time tag has
+// already passed when it is received.
+type LatePolicy int
+
+const (
+	// LateDispatch dispatches late bundles right away. This is the default.
+	LateDispatch LatePolicy = iota
+	// LateDrop drops late bundles.
+	LateDrop
+	// LateReport drops late bundles and reports a *LateBundleError to the
+	// ErrorHandler of the Server that received them.
+	LateReport
+)
+
+// LateBundleError is reported to Server.ErrorHandler for a bundle that is
+// dropped because it is late, see LateReport.
+type LateBundleError struct {
+	// Timetag is the time tag of the bundle.
+	Timetag Timetag
+	// Late is how late the bundle was when it was dispatched.
+	Late time.Duration
+}
+
+// Error implements the error interface.
+func (e *LateBundleError) Error() string {
+	return fmt.Sprintf("bundle dropped, %s late", e.Late)
+}
+
+// scheduler dispatches the elements of bundles at the time of their time
+// tags. The bundles wait in a heap ordered by time tag; bundles with the same
+// time tag are dispatched in the order in which they were scheduled. A single
+// goroutine waits for the time tags, it runs only while bundles are pending,
+// and hands the due bundles to goroutines that dispatch their elements.
+type scheduler struct {
+	d messageDispatcher
+
+	mu      sync.Mutex
+	queue   bundleQueue
+	seq     uint64
+	running bool
+	stopped bool
+	// dispatching is the number of bundles whose elements are being
+	// dispatched.
+	dispatching int
+	policy      LatePolicy
+	tolerance   time.Duration
+	// wake interrupts the wait of the goroutine when a bundle is added.
+	wake chan struct{}
+}
+
+// newScheduler returns a scheduler that dispatches the elements of the
+// bundles with `d`.
+func newScheduler(d messageDispatcher) *scheduler {
+	return &scheduler{d: d, wake: make(chan struct{}, 1)}
+}
+
+// scheduledBundle is a bundle waiting in a scheduler.
+type scheduledBundle struct {
+	ctx    context.Context
+	bundle *Bundle
+	at     time.Time
+	seq    uint64
+}
+
+// bundleQueue implements heap.Interface for the scheduled bundles.
+type bundleQueue []*scheduledBundle
+
+func (q bundleQueue) Len() int { return len(q) }
+
+func (q bundleQueue) Less(i, j int) bool {
+	if q[i].bundle.Timetag != q[j].bundle.Timetag {
+		return q[i].bundle.Timetag < q[j].bundle.Timetag
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q bundleQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *bundleQueue) Push(x interface{}) { *q = append(*q, x.(*scheduledBundle)) }
+
+func (q *bundleQueue) Pop() interface{} {
+	old := *q
+	b := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return b
+}
+
+// schedule adds `b` to the pending bundles, or handles it according to the
+// late policy if its time tag has passed. If `ctx` comes from
+// withInlineBundles, a bundle that is due is dispatched before schedule
+// returns.
+func (s *scheduler) schedule(ctx context.Context, b *Bundle) {
+	at := time.Now()
+	if b.Timetag > 1 {
+		at = b.Timetag.Time()
+	}
+
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	if late := time.Since(at); b.Timetag > 1 && late > s.tolerance && s.policy != LateDispatch {
+		policy := s.policy
+		s.mu.Unlock()
+		if report, ok := ctx.Value(errorReporterKey).(func(error)); ok && policy == LateReport {
+			report(&LateBundleError{Timetag: b.Timetag, Late: late})
+		}
+		return
+	}
+
+	if inline, _ := ctx.Value(inlineBundlesKey).(bool); inline && !at.After(time.Now()) {
+		s.dispatching++
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.dispatching--
+			s.mu.Unlock()
+		}()
+		dispatchElements(ctx, s.d, b)
+		return
+	}
+
+	s.seq++
+	heap.Push(&s.queue, &scheduledBundle{ctx: ctx, bundle: b, at: at, seq: s.seq})
+	if !s.running {
+		s.running = true
+		go s.run()
+	} else {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	s.mu.Unlock()
+}
+
+// run dispatches the pending bundles when they are due and returns once there
+// are none left.
+func (s *scheduler) run() {
+	for {
+		s.mu.Lock()
+		if s.stopped || len(s.queue) == 0 {
+			s.running = false
+			s.mu.Unlock()
+			return
+		}
+		next := s.queue[0]
+		wait := time.Until(next.at)
+		if wait <= 0 {
+			// The due bundles with the same time tag are dispatched one
+			// after the other in a goroutine of their own, so that a slow
+			// handler doesn't hold up the bundles that are due later.
+			var due []*scheduledBundle
+			for len(s.queue) > 0 && s.queue[0].bundle.Timetag == next.bundle.Timetag {
+				due = append(due, heap.Pop(&s.queue).(*scheduledBundle))
+			}
+			s.dispatching += len(due)
+			s.mu.Unlock()
+			go s.dispatch(due)
+			continue
+		}
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-s.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// dispatch dispatches the elements of the bundles `due` in order.
+func (s *scheduler) dispatch(due []*scheduledBundle) {
+	for _, b := range due {
+		dispatchElements(b.ctx, s.d, b.bundle)
+		s.mu.Lock()
+		s.dispatching--
+		s.mu.Unlock()
+	}
+}
+
+// stop discards the pending bundles and drops the ones scheduled afterwards.
+func (s *scheduler) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.queue = nil
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// pending returns the number of bundles that wait to be dispatched or are
+// being dispatched.
+func (s *scheduler) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue) + s.dispatching
+}
+
+// setLatePolicy sets the policy for the bundles that are more than
+// `tolerance` late.
+func (s *scheduler) setLatePolicy(policy LatePolicy, tolerance time.Duration) {
+	s.mu.Lock()
+	s.policy, s.tolerance = policy, tolerance
+	s.mu.Unlock()
+}
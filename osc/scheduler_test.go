@@ -0,0 +1,184 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerOrder(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var mu sync.Mutex
+		var calls []string
+		done := make(chan struct{})
+		d.AddMsgHandler("*", func(msg *Message) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, msg.Address)
+			if len(calls) == 5 {
+				close(done)
+			}
+		})
+
+		now := time.Now()
+		bundle := func(after time.Duration, addr string) *Bundle {
+			b := NewBundle(now.Add(after))
+			b.Append(NewMessage(addr))
+			return b
+		}
+		// Bundles with the same time tag are dispatched in the order in
+		// which they arrived.
+		d.Dispatch(bundle(60*time.Millisecond, "/d"))
+		d.Dispatch(bundle(20*time.Millisecond, "/a"))
+		d.Dispatch(bundle(40*time.Millisecond, "/b"))
+		d.Dispatch(bundle(40*time.Millisecond, "/c"))
+		d.Dispatch(bundle(60*time.Millisecond, "/e"))
+		if got := d.PendingBundles(); got != 5 {
+			t.Errorf("%s: PendingBundles() = %d, want = 5", td.name, got)
+		}
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatalf("%s: timed out waiting for the bundles", td.name)
+		}
+		mu.Lock()
+		if got, want := strings.Join(calls, ""), "/a/b/c/d/e"; got != want {
+			t.Errorf("%s: dispatched %s, want = %s", td.name, got, want)
+		}
+		mu.Unlock()
+		if elapsed := time.Since(now); elapsed < 60*time.Millisecond {
+			t.Errorf("%s: bundles dispatched after %s, want >= 60ms", td.name, elapsed)
+		}
+	}
+}
+
+func TestSchedulerStop(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		called := make(chan struct{}, 2)
+		d.AddMsgHandler("/a", func(msg *Message) { called <- struct{}{} })
+
+		b := NewBundle(time.Now().Add(50 * time.Millisecond))
+		b.Append(NewMessage("/a"))
+		d.Dispatch(b)
+		if got := d.PendingBundles(); got != 1 {
+			t.Errorf("%s: PendingBundles() = %d, want = 1", td.name, got)
+		}
+
+		d.Stop()
+		if got := d.PendingBundles(); got != 0 {
+			t.Errorf("%s: PendingBundles() after Stop() = %d, want = 0", td.name, got)
+		}
+		d.Dispatch(b)
+		if got := d.PendingBundles(); got != 0 {
+			t.Errorf("%s: PendingBundles() of a stopped dispatcher = %d, want = 0", td.name, got)
+		}
+
+		// Messages are still dispatched.
+		d.Dispatch(NewMessage("/a"))
+		<-called
+		select {
+		case <-called:
+			t.Errorf("%s: a bundle was dispatched after Stop()", td.name)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func TestSchedulerSlowBundle(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		release := make(chan struct{})
+		cue := make(chan time.Time, 1)
+		d.AddMsgHandler("/slow", func(msg *Message) { <-release })
+		d.AddMsgHandler("/cue", func(msg *Message) { cue <- time.Now() })
+
+		now := time.Now()
+		slow := NewBundle(now)
+		slow.Timetag = 1
+		slow.Append(NewMessage("/slow"))
+		d.Dispatch(slow)
+		b := NewBundle(now.Add(20 * time.Millisecond))
+		b.Append(NewMessage("/cue"))
+		d.Dispatch(b)
+
+		// The cue is dispatched on time while the slow bundle is still
+		// being dispatched.
+		select {
+		case at := <-cue:
+			if late := at.Sub(now); late > 200*time.Millisecond {
+				t.Errorf("%s: cue dispatched after %s, want ~20ms", td.name, late)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%s: the slow bundle held up the cue", td.name)
+		}
+		close(release)
+	}
+}
+
+func TestSchedulerLatePolicy(t *testing.T) {
+	for _, td := range testDispatchers {
+		for _, tt := range []struct {
+			desc     string
+			policy   LatePolicy
+			late     time.Duration
+			called   bool
+			reported bool
+		}{
+			{"dispatch", LateDispatch, time.Second, true, false},
+			{"drop", LateDrop, time.Second, false, false},
+			{"report", LateReport, time.Second, false, true},
+			{"within tolerance", LateDrop, 0, true, false},
+		} {
+			d := td.new()
+			d.SetLatePolicy(tt.policy, 100*time.Millisecond)
+			called := make(chan struct{}, 1)
+			d.AddMsgHandler("/a", func(msg *Message) { called <- struct{}{} })
+			var reported error
+			ctx := withErrorReporter(context.Background(), func(err error) { reported = err })
+
+			b := NewBundle(time.Now().Add(-tt.late))
+			b.Append(NewMessage("/a"))
+			d.DispatchContext(ctx, b)
+
+			select {
+			case <-called:
+				if !tt.called {
+					t.Errorf("%s: %s: late bundle was dispatched", td.name, tt.desc)
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tt.called {
+					t.Errorf("%s: %s: late bundle wasn't dispatched", td.name, tt.desc)
+				}
+			}
+			var lateErr *LateBundleError
+			if errors.As(reported, &lateErr) != tt.reported {
+				t.Errorf("%s: %s: reported %v, want reported = %t", td.name, tt.desc, reported, tt.reported)
+			}
+			if tt.reported && lateErr != nil && lateErr.Late < tt.late {
+				t.Errorf("%s: %s: LateBundleError.Late = %s, want >= %s", td.name, tt.desc, lateErr.Late, tt.late)
+			}
+		}
+	}
+}
+
+func BenchmarkSchedulerDispatch(b *testing.B) {
+	d := NewTrieDispatcher()
+	var wg sync.WaitGroup
+	d.AddMsgHandler("/a", func(msg *Message) { wg.Done() })
+	bundle := NewBundle(time.Now())
+	bundle.Timetag = 1
+	bundle.Append(NewMessage("/a"))
+
+	wg.Add(b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Dispatch(bundle)
+	}
+	wg.Wait()
+}
@@ -2,93 +2,523 @@ package osc
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
+	"log"
 	"net"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrServerClosed is returned by the Serve and ListenAndServe methods of a
+// Server after a call to Shutdown or Close.
+var ErrServerClosed = errors.New("osc: Server closed")
+
+// shutdownPollInterval is how often Shutdown checks whether the server is
+// idle.
+const shutdownPollInterval = 10 * time.Millisecond
+
 // Server represents an OSC server. The server listens on Address and Port for
-// incoming OSC packets and bundles.
+// incoming OSC packets and bundles. By default it receives UDP datagrams, set
+// Network to "tcp" to accept TCP connections instead.
 type Server struct {
-	Addr        string
+	Addr string
+	// Network is the network of Addr, e.g. "udp", "udp6" or "tcp". If empty,
+	// "udp" is used.
+	Network     string
 	Dispatcher  Dispatcher
 	ReadTimeout time.Duration
+	// Framing delimits the packets on stream connections, such as TCP
+	// connections. If nil, SizePrefixFraming is used.
+	Framing Framing
+	// MaxPacketSize is the maximum size of the packets on stream
+	// connections. If zero, MaxStreamPacketSize is used.
+	MaxPacketSize int
+	// Limits restricts the resources used to decode incoming packets. If nil,
+	// the DefaultLimits are enforced.
+	Limits *Limits
+	// ErrorHandler is called with the errors that occur while dispatching
+	// packets, e.g. a *PanicError if a handler panics. The panic is recovered,
+	// so the server keeps running. If nil, the errors are logged with the log
+	// package.
+	ErrorHandler func(err error)
+	// DeadLetters receives the packets that couldn't be decoded. If nil,
+	// they are passed to the ErrorHandler as a *PacketError instead. The
+	// packets are dropped if the channel isn't ready to receive. Either way
+	// the server keeps running.
+	DeadLetters chan<- *PacketError
+	// DispatchMode decides whether the packets are dispatched concurrently
+	// or in order, see the DispatchMode constants. Except with the default
+	// DispatchConcurrent, every call of Serve and ServeListener dispatches
+	// the packets of its connections with its own workers.
+	DispatchMode DispatchMode
+	// Workers is the number of workers of DispatchOrdered and
+	// DispatchBounded. If zero, runtime.GOMAXPROCS(0) workers are used.
+	Workers int
+	// QueueSize is the number of packets that wait for a worker, per worker
+	// with DispatchOrdered. If zero, a default size is used.
+	QueueSize int
+	// Overflow decides what happens with a packet if the queue is full.
+	Overflow OverflowPolicy
+
+	mu         sync.Mutex
+	conns      map[io.Closer]struct{}
+	inShutdown bool
+	// active is the number of running Serve loops and packets being
+	// dispatched.
+	active int
+}
+
+// bundleScheduler is implemented by the dispatchers that schedule bundles,
+// so Shutdown can wait for them.
+type bundleScheduler interface {
+	PendingBundles() int
 }
 
 // ListenAndServe retrieves incoming OSC packets and dispatches the retrieved
-// OSC packets.
+// OSC packets. It always returns a non-nil error, ErrServerClosed after a call
+// to Shutdown or Close.
 func (s *Server) ListenAndServe() error {
+	return s.ListenAndServeContext(context.Background())
+}
+
+// ListenAndServeContext is like ListenAndServe, but the server is closed
+// when `ctx` is done, see Close. The handlers receive a context derived from
+// `ctx`.
+func (s *Server) ListenAndServeContext(ctx context.Context) error {
 	if s.Dispatcher == nil {
 		s.Dispatcher = NewStandardDispatcher()
 	}
 
-	ln, err := net.ListenPacket("udp", s.Addr)
+	network := s.Network
+	if network == "" {
+		network = "udp"
+	}
+	var ln io.Closer
+	var err error
+	if strings.HasPrefix(network, "tcp") {
+		ln, err = net.Listen(network, s.Addr)
+	} else {
+		ln, err = net.ListenPacket(network, s.Addr)
+	}
 	if err != nil {
 		return err
 	}
 	defer ln.Close()
 
-	return s.Serve(ln)
+	if ctx.Done() != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.Close()
+			case <-stop:
+			}
+		}()
+	}
+
+	if l, ok := ln.(net.Listener); ok {
+		return s.serveListener(ctx, l)
+	}
+	return s.serve(ctx, ln.(net.PacketConn))
 }
 
 // Serve retrieves incoming OSC packets from the given connection and dispatches
-// retrieved OSC packets. If something goes wrong an error is returned.
+// retrieved OSC packets. The packets that can't be decoded are passed to
+// DeadLetters or the ErrorHandler, and Serve goes on. It returns if reading
+// from the connection fails, and ErrServerClosed after a call to Shutdown or
+// Close.
 func (s *Server) Serve(c net.PacketConn) error {
+	return s.serve(context.Background(), c)
+}
+
+// serve is Serve with the base context `ctx` of the handlers.
+func (s *Server) serve(ctx context.Context, c net.PacketConn) error {
+	if !s.trackConn(c, true) {
+		return ErrServerClosed
+	}
+	defer s.trackConn(c, false)
+
+	queue := newPacketQueue(s)
+	if queue != nil {
+		defer queue.close()
+	}
+
+	ctx = withErrorReporter(ctx, s.reportError)
+	var tempDelay time.Duration
+	for {
+		msg, addr, err := s.readFromConnection(c)
+		var pe *PacketError
+		if errors.As(err, &pe) {
+			tempDelay = 0
+			s.deadLetter(pe)
+			continue
+		}
+		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				tempDelay = backoff(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+		info := &MessageInfo{RemoteAddr: addr, LocalAddr: c.LocalAddr(), Conn: c, ReceivedAt: time.Now()}
+		s.enqueue(withMessageInfo(ctx, info), queue, msg)
+	}
+}
+
+// ServeListener accepts incoming connections on the listener `l`, e.g. a TCP
+// listener, and dispatches the OSC packets received on them. Each connection
+// is served in a goroutine of its own, the handlers can reply over the
+// connection with Reply. It returns if accepting fails, and ErrServerClosed
+// after a call to Shutdown or Close.
+func (s *Server) ServeListener(l net.Listener) error {
+	return s.serveListener(context.Background(), l)
+}
+
+// serveListener is ServeListener with the base context `ctx` of the handlers.
+func (s *Server) serveListener(ctx context.Context, l net.Listener) error {
+	if !s.trackConn(l, true) {
+		return ErrServerClosed
+	}
+	defer s.trackConn(l, false)
+
+	// The connections share the workers, which stop once all connections
+	// are closed.
+	var conns sync.WaitGroup
+	queue := newPacketQueue(s)
+	if queue != nil {
+		defer func() {
+			go func() {
+				conns.Wait()
+				queue.close()
+			}()
+		}()
+	}
+
+	ctx = withErrorReporter(ctx, s.reportError)
 	var tempDelay time.Duration
 	for {
-		msg, err := s.readFromConnection(c)
+		c, err := l.Accept()
 		if err != nil {
+			if s.shuttingDown() {
+				return ErrServerClosed
+			}
 			if ne, ok := err.(net.Error); ok && ne.Temporary() {
-				if tempDelay == 0 {
-					tempDelay = 5 * time.Millisecond
-				} else {
-					tempDelay *= 2
-				}
-				if max := 1 * time.Second; tempDelay > max {
-					tempDelay = max
-				}
-				time.Sleep(tempDelay)
+				tempDelay = backoff(tempDelay)
 				continue
 			}
 			return err
 		}
 		tempDelay = 0
-		go s.Dispatcher.Dispatch(msg)
+		if !s.trackConn(c, true) {
+			c.Close()
+			return ErrServerClosed
+		}
+
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			defer s.trackConn(c, false)
+			defer c.Close()
+			err := s.serveStream(ctx, s.newStreamConn(c), queue)
+			if err != io.EOF && !s.shuttingDown() {
+				s.reportError(err)
+			}
+		}()
+	}
+}
+
+// ServeStream dispatches the OSC packets received on the stream `rw`, e.g. a
+// serial port, with the Framing of the server. The handlers can reply over
+// the stream with Reply. It returns nil when the stream ends, the error if
+// reading from it fails, and ErrServerClosed after a call to Shutdown or
+// Close. They close `rw` if it is an io.Closer, otherwise Shutdown waits
+// until the stream ends.
+func (s *Server) ServeStream(rw io.ReadWriter) error {
+	closer, ok := rw.(io.Closer)
+	if !ok {
+		closer = &nopCloser{rw}
+	}
+	if !s.trackConn(closer, true) {
+		return ErrServerClosed
+	}
+	defer s.trackConn(closer, false)
+
+	queue := newPacketQueue(s)
+	if queue != nil {
+		defer queue.close()
+	}
+
+	ctx := withErrorReporter(context.Background(), s.reportError)
+	err := s.serveStream(ctx, s.newStreamConn(rw), queue)
+	switch {
+	case s.shuttingDown():
+		return ErrServerClosed
+	case err == io.EOF:
+		return nil
+	}
+	return err
+}
+
+// nopCloser is the io.Closer of a stream that can't be closed.
+type nopCloser struct {
+	io.ReadWriter
+}
+
+// Close implements the io.Closer interface.
+func (*nopCloser) Close() error {
+	return nil
+}
+
+// newStreamConn returns a StreamConn for `rw` with the settings of the
+// server.
+func (s *Server) newStreamConn(rw io.ReadWriter) *StreamConn {
+	c := NewStreamConn(rw)
+	c.Framing = s.Framing
+	c.MaxPacketSize = s.MaxPacketSize
+	c.ReadTimeout = s.ReadTimeout
+	c.Limits = s.Limits
+	return c
+}
+
+// serveStream dispatches the packets received on `c` until the stream ends or
+// fails. `ctx` must carry the error reporter of the server.
+func (s *Server) serveStream(ctx context.Context, c *StreamConn, queue *packetQueue) error {
+	for {
+		p, err := c.Receive()
+		var pe *PacketError
+		if errors.As(err, &pe) {
+			s.deadLetter(pe)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		info := &MessageInfo{RemoteAddr: c.RemoteAddr(), LocalAddr: c.LocalAddr(), Stream: c, ReceivedAt: time.Now()}
+		s.enqueue(withMessageInfo(ctx, info), queue, p)
+	}
+}
+
+// enqueue hands `p` to the workers of `queue`, or dispatches it in a
+// goroutine of its own if `queue` is nil.
+func (s *Server) enqueue(ctx context.Context, queue *packetQueue, p Packet) {
+	s.addActive(1)
+	if queue != nil {
+		queue.push(ctx, p)
+		return
+	}
+	go s.dispatch(ctx, p)
+}
+
+// backoff returns how long to sleep after a temporary error, given the
+// previous delay, and sleeps that long.
+func backoff(delay time.Duration) time.Duration {
+	if delay == 0 {
+		delay = 5 * time.Millisecond
+	} else {
+		delay *= 2
+	}
+	if max := 1 * time.Second; delay > max {
+		delay = max
+	}
+	time.Sleep(delay)
+	return delay
+}
+
+// trackConn adds `c` to the connections that are served, or removes it. It
+// returns false if `c` can't be added because the server is shutting down.
+func (s *Server) trackConn(c io.Closer, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.conns, c)
+		s.active--
+		return true
+	}
+	if s.inShutdown {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[io.Closer]struct{})
+	}
+	s.conns[c] = struct{}{}
+	s.active++
+	return true
+}
+
+// addActive adds `n` to the number of packets being dispatched.
+func (s *Server) addActive(n int) {
+	s.mu.Lock()
+	s.active += n
+	s.mu.Unlock()
+}
+
+// shuttingDown reports whether Shutdown or Close has been called.
+func (s *Server) shuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inShutdown
+}
+
+// closeConns stops the server and closes the connections that are served.
+func (s *Server) closeConns() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inShutdown = true
+	var err error
+	for c := range s.conns {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Close immediately closes the connections that are served, so Serve returns
+// ErrServerClosed. It doesn't wait for the handlers that are running or for
+// the bundles that are pending, use Shutdown for that.
+func (s *Server) Close() error {
+	return s.closeConns()
+}
+
+// Shutdown gracefully shuts down the server. It closes the connections that
+// are served, then waits until the packets that were received have been
+// dispatched, including the bundles that wait for their time tag if the
+// Dispatcher schedules bundles. If `ctx` is done first, Shutdown returns its
+// error.
+//
+// Once Shutdown has been called, Serve and ListenAndServe return
+// ErrServerClosed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.closeConns()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for !s.idle() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return err
+}
+
+// idle reports whether no packets are being dispatched and no bundles are
+// pending.
+func (s *Server) idle() bool {
+	s.mu.Lock()
+	active := s.active
+	s.mu.Unlock()
+	if active != 0 {
+		return false
+	}
+	if bs, ok := s.Dispatcher.(bundleScheduler); ok {
+		return bs.PendingBundles() == 0
+	}
+	return true
+}
+
+// dispatch dispatches `p` and recovers the panics that aren't recovered by
+// the dispatcher itself.
+func (s *Server) dispatch(ctx context.Context, p Packet) {
+	defer s.addActive(-1)
+	defer func() {
+		if v := recover(); v != nil {
+			err := &PanicError{Value: v, Stack: debug.Stack()}
+			if msg, ok := p.(*Message); ok {
+				err.Address = msg.Address
+			}
+			s.reportError(err)
+		}
+	}()
+	dispatch(ctx, s.Dispatcher, p)
+}
+
+// deadLetter passes the packet that couldn't be decoded to DeadLetters, or
+// reports it if there is no dead-letter channel.
+func (s *Server) deadLetter(pe *PacketError) {
+	if s.DeadLetters == nil {
+		s.reportError(pe)
+		return
+	}
+	select {
+	case s.DeadLetters <- pe:
+	default:
+	}
+}
+
+// reportError passes `err` to the ErrorHandler, or logs it if there is none.
+func (s *Server) reportError(err error) {
+	if s.ErrorHandler != nil {
+		s.ErrorHandler(err)
+		return
 	}
+	if pe, ok := err.(*PanicError); ok {
+		log.Printf("osc: %s\n%s", pe, pe.Stack)
+		return
+	}
+	log.Printf("osc: %s", err)
 }
 
-// ReceivePacket listens for incoming OSC packets and returns the packet if one is received.
+// ReceivePacket listens for incoming OSC packets and returns the packet if one
+// is received. A *PacketError is returned if the packet can't be decoded.
 func (s *Server) ReceivePacket(c net.PacketConn) (Packet, error) {
+	p, _, err := s.readFromConnection(c)
+	return p, err
+}
+
+// ReceivePacketFrom listens for incoming OSC packets and returns the packet
+// and the address of its sender if one is received. A *PacketError is
+// returned if the packet can't be decoded.
+func (s *Server) ReceivePacketFrom(c net.PacketConn) (Packet, net.Addr, error) {
 	return s.readFromConnection(c)
 }
 
 type eofReader struct {
 	net.PacketConn
+	addr net.Addr
 }
 
-func (g eofReader) Read(buf []byte) (int, error) {
-	n, _, err := g.ReadFrom(buf)
+func (g *eofReader) Read(buf []byte) (int, error) {
+	n, addr, err := g.ReadFrom(buf)
 	if err == nil {
+		g.addr = addr
 		return n, io.EOF
 	}
 	return n, err
 }
 
-// readFromConnection retrieves OSC packets.
-func (s *Server) readFromConnection(c net.PacketConn) (Packet, error) {
+// readFromConnection retrieves OSC packets. The errors of the connection are
+// returned as they are, the packets that can't be decoded as a *PacketError.
+func (s *Server) readFromConnection(c net.PacketConn) (Packet, net.Addr, error) {
 	if s.ReadTimeout != 0 {
 		if err := c.SetReadDeadline(time.Now().Add(s.ReadTimeout)); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	b := bufPool.Get().(*bytes.Buffer)
 	defer bufPool.Put(b)
 	b.Reset()
-	_, err := b.ReadFrom(eofReader{c})
+	r := eofReader{PacketConn: c}
+	_, err := b.ReadFrom(&r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return ReadPacket(b.Bytes())
+	p, err := ReadPacketWithLimits(b.Bytes(), s.Limits)
+	if err != nil {
+		data := append([]byte(nil), b.Bytes()...)
+		return nil, r.addr, &PacketError{Data: data, Addr: r.addr, Err: err}
+	}
+	return p, r.addr, nil
 }
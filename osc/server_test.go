@@ -1,8 +1,11 @@
 package osc
 
 import (
+	"context"
+	"errors"
 	"net"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -18,7 +21,8 @@ func TestServerMessageReceiving(t *testing.T) {
 		server := &Server{}
 		c, err := net.ListenPacket("udp", "localhost:6677")
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 		defer c.Close()
 
@@ -105,19 +109,22 @@ func TestReadTimeout(t *testing.T) {
 
 		select {
 		case <-time.After(5 * time.Second):
-			t.Fatal("timed out")
+			t.Error("timed out")
+			return
 		case <-start:
 			client := NewClient("localhost", 6677)
 			msg := NewMessage("/address/test1")
 			err := client.Send(msg)
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 			time.Sleep(150 * time.Millisecond)
 			msg = NewMessage("/address/test2")
 			err = client.Send(msg)
 			if err != nil {
-				t.Fatal(err)
+				t.Error(err)
+				return
 			}
 		}
 	}()
@@ -128,7 +135,8 @@ func TestReadTimeout(t *testing.T) {
 		server := &Server{ReadTimeout: 100 * time.Millisecond}
 		c, err := net.ListenPacket("udp", "localhost:6677")
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
+			return
 		}
 		defer c.Close()
 
@@ -164,6 +172,14 @@ func TestReadTimeout(t *testing.T) {
 	wg.Wait()
 }
 
+func TestServerLimits(t *testing.T) {
+	d := &dummyConn{m: msg}
+	s := &Server{Limits: &Limits{MaxAddressLength: 8}}
+	if _, err := s.ReceivePacket(d); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ReceivePacket() error = %v, want = %v", err, ErrLimitExceeded)
+	}
+}
+
 func BenchmarkReceivePacket(b *testing.B) {
 	d := &dummyConn{m: msg}
 	s := &Server{}
@@ -175,3 +191,349 @@ func BenchmarkReceivePacket(b *testing.B) {
 	}
 	result = p
 }
+
+// serveTest starts `s` on a local UDP socket and returns a connection to it.
+// The server is stopped when the test ends.
+func serveTest(t *testing.T, s *Server) net.Conn {
+	t.Helper()
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(c)
+	t.Cleanup(func() { c.Close() })
+
+	conn, err := net.Dial("udp", c.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// sendTest writes the packets `ps` to `conn`.
+func sendTest(t *testing.T, conn net.Conn, ps ...Packet) {
+	t.Helper()
+	for _, p := range ps {
+		b, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = conn.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServerPanicRecovery(t *testing.T) {
+	errs := make(chan error, 4)
+	handled := make(chan string, 4)
+
+	d := NewStandardDispatcher()
+	d.AddMsgHandler("/panic", func(msg *Message) { panic("boom") })
+	d.AddMsgHandler("/ok", func(msg *Message) { handled <- msg.Address })
+	conn := serveTest(t, &Server{Dispatcher: d, ErrorHandler: func(err error) { errs <- err }})
+
+	b := NewBundle(time.Now())
+	b.Append(NewMessage("/panic"))
+	sendTest(t, conn, NewMessage("/panic"), b)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			var pe *PanicError
+			if !errors.As(err, &pe) {
+				t.Fatalf("ErrorHandler() error = %v, want a *PanicError", err)
+			}
+			if pe.Address != "/panic" || pe.Value != "boom" || len(pe.Stack) == 0 {
+				t.Errorf("PanicError = %+v, want address /panic, value boom and a stack", pe)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the panic to be reported")
+		}
+	}
+
+	// The server keeps running.
+	sendTest(t, conn, NewMessage("/ok"))
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the message after the panic")
+	}
+}
+
+// panicDispatcher is a Dispatcher that panics on every packet.
+type panicDispatcher struct{}
+
+func (panicDispatcher) Dispatch(packet Packet) { panic(errors.New("boom")) }
+
+func TestServerPanicRecoveryDispatcher(t *testing.T) {
+	errs := make(chan error, 1)
+	conn := serveTest(t, &Server{Dispatcher: panicDispatcher{}, ErrorHandler: func(err error) { errs <- err }})
+	sendTest(t, conn, NewMessage("/a"))
+
+	select {
+	case err := <-errs:
+		var pe *PanicError
+		if !errors.As(err, &pe) || pe.Address != "/a" {
+			t.Errorf("ErrorHandler() error = %v, want a *PanicError for /a", err)
+		}
+		if err.Error() != "panic while handling /a: boom" {
+			t.Errorf("Error() = %q", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the panic to be reported")
+	}
+}
+
+func TestDispatchPanicWithoutServer(t *testing.T) {
+	d := NewStandardDispatcher()
+	d.AddMsgHandler("/panic", func(msg *Message) { panic("boom") })
+	defer func() {
+		if recover() == nil {
+			t.Error("Dispatch() expected a panic")
+		}
+	}()
+	d.Dispatch(NewMessage("/panic"))
+}
+
+func TestServerMessageInfo(t *testing.T) {
+	infos := make(chan *MessageInfo, 2)
+	d := NewStandardDispatcher()
+	d.AddHandler("/info", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+		info, _ := MessageInfoFromContext(ctx)
+		infos <- info
+	}))
+	conn := serveTest(t, &Server{Dispatcher: d})
+
+	start := time.Now()
+	b := NewBundle(time.Now())
+	b.Append(NewMessage("/info"))
+	sendTest(t, conn, NewMessage("/info"))
+	sendTest(t, conn, b)
+
+	// The packets are dispatched concurrently, so they may arrive in any
+	// order.
+	timetags := map[Timetag]bool{0: true, b.Timetag: true}
+	for i := 0; i < 2; i++ {
+		select {
+		case info := <-infos:
+			if info == nil {
+				t.Fatal("MessageInfoFromContext() returned no info")
+			}
+			if got, want := info.RemoteAddr.String(), conn.LocalAddr().String(); got != want {
+				t.Errorf("RemoteAddr = %s, want = %s", got, want)
+			}
+			if got, want := info.LocalAddr.String(), conn.RemoteAddr().String(); got != want {
+				t.Errorf("LocalAddr = %s, want = %s", got, want)
+			}
+			if info.Conn == nil {
+				t.Error("Conn = nil")
+			}
+			if info.ReceivedAt.Before(start) {
+				t.Errorf("ReceivedAt = %s, want after %s", info.ReceivedAt, start)
+			}
+			if !timetags[info.Timetag] {
+				t.Errorf("Timetag = %d, want = 0 or %d", info.Timetag, b.Timetag)
+			}
+			delete(timetags, info.Timetag)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the message")
+		}
+	}
+}
+
+func TestReceivePacketFrom(t *testing.T) {
+	d := &dummyConn{m: msg}
+	s := &Server{}
+	p, addr, err := s.ReceivePacketFrom(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || addr == nil {
+		t.Errorf("ReceivePacketFrom() = %v, %v, want a packet and an address", p, addr)
+	}
+}
+
+func TestServerShutdown(t *testing.T) {
+	d := NewStandardDispatcher()
+	started := make(chan struct{})
+	var slow, cue int32
+	d.AddMsgHandler("/slow", func(msg *Message) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		atomic.StoreInt32(&slow, 1)
+	})
+	d.AddMsgHandler("/cue", func(msg *Message) { atomic.StoreInt32(&cue, 1) })
+
+	s := &Server{Dispatcher: d}
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(c) }()
+	conn, err := net.Dial("udp", c.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	b := NewBundle(time.Now().Add(100 * time.Millisecond))
+	b.Append(NewMessage("/cue"))
+	sendTest(t, conn, b, NewMessage("/slow"))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() unexpected error: %s", err)
+	}
+	if atomic.LoadInt32(&slow) != 1 {
+		t.Error("Shutdown() returned before the handler finished")
+	}
+	if atomic.LoadInt32(&cue) != 1 {
+		t.Error("Shutdown() returned before the scheduled bundle was dispatched")
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() error = %v, want = %v", err, ErrServerClosed)
+	}
+	if err := s.Serve(c); err != ErrServerClosed {
+		t.Errorf("Serve() after Shutdown() error = %v, want = %v", err, ErrServerClosed)
+	}
+}
+
+func TestServerShutdownTimeout(t *testing.T) {
+	d := NewTrieDispatcher()
+	defer d.Stop()
+	d.AddMsgHandler("/cue", func(msg *Message) {})
+	s := &Server{Dispatcher: d}
+	conn := serveTest(t, s)
+
+	b := NewBundle(time.Now().Add(time.Hour))
+	b.Append(NewMessage("/cue"))
+	sendTest(t, conn, b)
+	for d.PendingBundles() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() error = %v, want = %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	s := &Server{Dispatcher: NewStandardDispatcher()}
+	c, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(c) }()
+	waitServing(s)
+
+	if err := s.Close(); err != nil {
+		t.Errorf("Close() unexpected error: %s", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Serve() error = %v, want = %v", err, ErrServerClosed)
+	}
+}
+
+func TestListenAndServeContext(t *testing.T) {
+	s := &Server{Addr: "127.0.0.1:0", Dispatcher: NewStandardDispatcher()}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.ListenAndServeContext(ctx) }()
+	waitServing(s)
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != ErrServerClosed {
+			t.Errorf("ListenAndServeContext() error = %v, want = %v", err, ErrServerClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ListenAndServeContext() didn't return after the context was canceled")
+	}
+}
+
+// waitServing waits until `s` serves a connection.
+func waitServing(s *Server) {
+	for {
+		s.mu.Lock()
+		n := len(s.conns)
+		s.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerBadPacket(t *testing.T) {
+	received := make(chan string, 1)
+	d := NewStandardDispatcher()
+	d.AddMsgHandler("/a", func(msg *Message) { received <- msg.Address })
+	deadLetters := make(chan *PacketError, 1)
+	var reported []error
+	s := &Server{
+		Dispatcher:   d,
+		DeadLetters:  deadLetters,
+		ErrorHandler: func(err error) { reported = append(reported, err) },
+	}
+	conn := serveTest(t, s)
+
+	bad := []byte("garbage")
+	if _, err := conn.Write(bad); err != nil {
+		t.Fatal(err)
+	}
+	sendTest(t, conn, NewMessage("/a"))
+
+	select {
+	case pe := <-deadLetters:
+		if string(pe.Data) != string(bad) {
+			t.Errorf("PacketError.Data = %q, want = %q", pe.Data, bad)
+		}
+		if pe.Addr == nil || pe.Addr.String() != conn.LocalAddr().String() {
+			t.Errorf("PacketError.Addr = %v, want = %v", pe.Addr, conn.LocalAddr())
+		}
+		if !errors.Is(pe, ErrBadAddress) {
+			t.Errorf("PacketError.Err = %v, want = %v", pe.Err, ErrBadAddress)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the dead letter")
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("the server stopped after a bad packet")
+	}
+	if len(reported) != 0 {
+		t.Errorf("ErrorHandler called with %v, want no errors", reported)
+	}
+}
+
+func TestServerBadPacketErrorHandler(t *testing.T) {
+	reported := make(chan error, 1)
+	s := &Server{
+		Dispatcher:   NewStandardDispatcher(),
+		ErrorHandler: func(err error) { reported <- err },
+	}
+	conn := serveTest(t, s)
+	if _, err := conn.Write([]byte("garbage")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-reported:
+		var pe *PacketError
+		if !errors.As(err, &pe) {
+			t.Errorf("ErrorHandler called with %T, want = *PacketError", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the error")
+	}
+}
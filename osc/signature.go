@@ -0,0 +1,420 @@
+package osc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+////
+// Type tag signature routing
+////
+
+// routeKey identifies a route by its OSC address and its optional type tag
+// pattern.
+type routeKey struct {
+	addr     string
+	typeTags string
+}
+
+// parseRouteKey parses the address under which a handler is registered. The
+// OSC address may be followed by a space and a type tag pattern, e.g.
+// "/fader ,f". The handler is then only called for messages whose type tags
+// match the pattern, see signature for its syntax.
+func parseRouteKey(s string) (routeKey, *signature, error) {
+	key := routeKey{addr: s}
+	if s == "*" {
+		return key, nil, nil
+	}
+
+	i := strings.IndexByte(s, ' ')
+	if i >= 0 {
+		key = routeKey{addr: s[:i], typeTags: s[i+1:]}
+	}
+	if err := validateAddress(key.addr); err != nil {
+		return key, nil, err
+	}
+	if i < 0 {
+		return key, nil, nil
+	}
+
+	if !strings.HasPrefix(key.typeTags, ",") {
+		return key, nil, fmt.Errorf("%w: %q: type tags must start with ','", ErrBadPattern, key.typeTags)
+	}
+	sig, err := parseSignature(key.typeTags)
+	if err != nil {
+		return key, nil, fmt.Errorf("%w: %q: %s", ErrBadPattern, key.typeTags, err)
+	}
+	return key, sig, nil
+}
+
+// signature is a parsed type tag pattern. '?' matches a single argument,
+// which is either a type tag or an array, '*' any number of arguments and
+// '{...}' one of the listed type tag strings. '[' and ']' enclose the pattern
+// of the elements of an array, so ",[ii]" only matches an array of two int32s
+// and ",[*]" any single array.
+type signature struct {
+	// lit is the type tag pattern.
+	lit   string
+	elems []sigElem
+	// exact is set if the pattern contains no wildcards, it then only
+	// matches the type tag string `lit`.
+	exact bool
+}
+
+// sigKind is the kind of an element of a type tag pattern.
+type sigKind uint8
+
+const (
+	sigTag   sigKind = iota // a type tag
+	sigArray                // '[...]'
+	sigAny                  // '?'
+	sigStar                 // '*'
+	sigAlt                  // '{...}'
+)
+
+// sigElem is a single element of a type tag pattern.
+type sigElem struct {
+	kind sigKind
+	// tag is the type tag of a sigTag.
+	tag byte
+	// elems is the pattern of the elements of a sigArray.
+	elems []sigElem
+	// alts holds the arguments of each type tag string of a sigAlt.
+	alts [][]string
+}
+
+// parseSignature parses the type tag pattern `s`, which starts with a ','.
+func parseSignature(s string) (*signature, error) {
+	elems, rest, err := parseSigElems(s[1:])
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, errors.New("unbalanced ']'")
+	}
+	return &signature{lit: s, elems: elems, exact: !strings.ContainsAny(s, "?*{")}, nil
+}
+
+// parseSigElems parses the elements of a type tag pattern up to the ']' that
+// ends the array they are in, or up to the end of `s`. It returns the rest of
+// `s`, starting with the ']'.
+func parseSigElems(s string) ([]sigElem, string, error) {
+	var elems []sigElem
+
+	for len(s) > 0 {
+		switch s[0] {
+		case ']':
+			return elems, s, nil
+
+		case '[':
+			sub, rest, err := parseSigElems(s[1:])
+			if err != nil {
+				return nil, "", err
+			}
+			if rest == "" {
+				return nil, "", errors.New("unbalanced '['")
+			}
+			elems = append(elems, sigElem{kind: sigArray, elems: sub})
+			s = rest[1:]
+
+		case '?':
+			elems = append(elems, sigElem{kind: sigAny})
+			s = s[1:]
+
+		case '*':
+			// Consecutive stars are equivalent to a single one.
+			if n := len(elems); n == 0 || elems[n-1].kind != sigStar {
+				elems = append(elems, sigElem{kind: sigStar})
+			}
+			s = s[1:]
+
+		case '{':
+			end := strings.IndexByte(s, '}')
+			if end < 0 {
+				return nil, "", errors.New("unbalanced '{'")
+			}
+			e := sigElem{kind: sigAlt}
+			for _, alt := range strings.Split(s[1:end], ",") {
+				if strings.ContainsAny(alt, "?*{") || !balancedArrays(alt) {
+					return nil, "", fmt.Errorf("invalid type tags in %q", s[:end+1])
+				}
+				e.alts = append(e.alts, splitTypeTags(alt))
+			}
+			elems = append(elems, e)
+			s = s[end+1:]
+
+		case '}':
+			return nil, "", errors.New("unbalanced '}'")
+
+		default:
+			elems = append(elems, sigElem{kind: sigTag, tag: s[0]})
+			s = s[1:]
+		}
+	}
+
+	return elems, "", nil
+}
+
+// balancedArrays reports whether the array type tags '[' and ']' in `s` are
+// balanced.
+func balancedArrays(s string) bool {
+	depth := 0
+	for _, c := range s {
+		if c == '[' {
+			depth++
+		} else if c == ']' {
+			depth--
+		}
+		if depth < 0 {
+			return false
+		}
+	}
+	return depth == 0
+}
+
+// splitTypeTags splits the type tags `s` into the type tags of the single
+// arguments. An array is a single argument, from its '[' to its ']'.
+func splitTypeTags(s string) []string {
+	var args []string
+	for len(s) > 0 {
+		n := 1
+		for depth := 0; n <= len(s); n++ {
+			if s[n-1] == '[' {
+				depth++
+			} else if s[n-1] == ']' {
+				depth--
+			}
+			if depth <= 0 {
+				break
+			}
+		}
+		if n > len(s) {
+			n = len(s)
+		}
+		args, s = append(args, s[:n]), s[n:]
+	}
+	return args
+}
+
+// match reports whether the type tag string `tags` matches the pattern.
+func (sig *signature) match(tags string) bool {
+	if sig.exact {
+		return tags == sig.lit
+	}
+	if !strings.HasPrefix(tags, ",") {
+		return false
+	}
+	return matchSigElems(sig.elems, splitTypeTags(tags[1:]))
+}
+
+// matchSigElems reports whether the arguments with the type tags `args`
+// match the pattern elements `elems`.
+func matchSigElems(elems []sigElem, args []string) bool {
+	m := sigMatcher{elems: elems, args: args}
+	return m.match(0, 0)
+}
+
+// sigMatcher matches the elements of a type tag pattern against the type
+// tags of the arguments. The results of the '*' and '{...}' elements for
+// each pair of positions are memorized to keep the matching time polynomial.
+type sigMatcher struct {
+	elems []sigElem
+	args  []string
+	memo  []uint8
+}
+
+// match reports whether the elements from index `e` on match the arguments
+// from index `a` on.
+func (m *sigMatcher) match(e, a int) bool {
+	for ; e < len(m.elems); e++ {
+		el := &m.elems[e]
+		if el.kind == sigStar || el.kind == sigAlt {
+			return m.branch(e, a)
+		}
+		if a == len(m.args) || !el.matchArg(m.args[a]) {
+			return false
+		}
+		a++
+	}
+
+	return a == len(m.args)
+}
+
+// branch matches the '*' or '{...}' element at index `e` and everything that
+// follows it against the arguments from index `a` on.
+func (m *sigMatcher) branch(e, a int) bool {
+	if m.memo == nil {
+		m.memo = make([]uint8, (len(m.elems)+1)*(len(m.args)+1))
+	}
+	idx := e*(len(m.args)+1) + a
+	if r := m.memo[idx]; r != memoUnknown {
+		return r == memoTrue
+	}
+
+	ok := false
+	if el := &m.elems[e]; el.kind == sigStar {
+		for j := a; j <= len(m.args) && !ok; j++ {
+			ok = m.match(e+1, j)
+		}
+	} else {
+		for _, alt := range el.alts {
+			if hasArgs(m.args[a:], alt) && m.match(e+1, a+len(alt)) {
+				ok = true
+				break
+			}
+		}
+	}
+
+	m.memo[idx] = memoFalse
+	if ok {
+		m.memo[idx] = memoTrue
+	}
+	return ok
+}
+
+// matchArg reports whether the argument with the type tags `arg` matches the
+// element, which is neither a '*' nor a '{...}'.
+func (el *sigElem) matchArg(arg string) bool {
+	switch el.kind {
+	case sigTag:
+		return len(arg) == 1 && arg[0] == el.tag
+	case sigArray:
+		return len(arg) >= 2 && arg[0] == '[' && matchSigElems(el.elems, splitTypeTags(arg[1:len(arg)-1]))
+	}
+	return true
+}
+
+// hasArgs reports whether `args` starts with the arguments `prefix`.
+func hasArgs(args, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i := range prefix {
+		if args[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// routeSet holds the routes of an OSC address: the routes with a type tag
+// pattern, in the order in which they were added, and the fallback route
+// without one. A routeSet is never modified, changes return a copy.
+type routeSet struct {
+	typed    []*route
+	fallback *route
+}
+
+// get returns the route with the type tag pattern `typeTags`, or nil.
+func (s *routeSet) get(typeTags string) *route {
+	if s == nil {
+		return nil
+	}
+	if typeTags == "" {
+		return s.fallback
+	}
+	for _, r := range s.typed {
+		if r.typeTags == typeTags {
+			return r
+		}
+	}
+	return nil
+}
+
+// with returns a copy of the set in which the route with the type tag pattern
+// `typeTags` is `r`. A nil route removes it. If the set is empty afterwards,
+// nil is returned.
+func (s *routeSet) with(typeTags string, r *route) *routeSet {
+	c := &routeSet{}
+	if s != nil {
+		c.fallback = s.fallback
+	}
+
+	if typeTags == "" {
+		c.fallback = r
+	}
+	found := false
+	for _, old := range s.routes() {
+		if old.typeTags != typeTags {
+			c.typed = append(c.typed, old)
+		} else if r != nil {
+			c.typed = append(c.typed, r)
+			found = true
+		}
+	}
+	if typeTags != "" && r != nil && !found {
+		c.typed = append(c.typed, r)
+	}
+
+	if c.fallback == nil && len(c.typed) == 0 {
+		return nil
+	}
+	return c
+}
+
+// routes returns the routes with a type tag pattern of a set that may be nil.
+func (s *routeSet) routes() []*route {
+	if s == nil {
+		return nil
+	}
+	return s.typed
+}
+
+// wrap returns a copy of the set in which the handlers are wrapped with the
+// dispatcher middleware `middleware`.
+func (s *routeSet) wrap(middleware []Middleware) *routeSet {
+	c := &routeSet{typed: make([]*route, len(s.typed))}
+	for i, r := range s.typed {
+		c.typed[i] = r.wrap(middleware)
+	}
+	if s.fallback != nil {
+		c.fallback = s.fallback.wrap(middleware)
+	}
+	return c
+}
+
+// match returns the route for a message with the type tags `tags`. A type
+// tag pattern without wildcards takes precedence over the ones with
+// wildcards, which are tried in the order in which they were added. If none
+// matches, the fallback route is returned, which may be nil.
+func (s *routeSet) match(tags *typeTags) *route {
+	if len(s.typed) == 0 {
+		return s.fallback
+	}
+	str, ok := tags.get()
+	if !ok {
+		return s.fallback
+	}
+
+	for _, r := range s.typed {
+		if r.sig.exact && r.sig.match(str) {
+			return r
+		}
+	}
+	for _, r := range s.typed {
+		if r.sig.match(str) {
+			return r
+		}
+	}
+	return s.fallback
+}
+
+// typeTags computes the type tags of a message once they are needed.
+type typeTags struct {
+	msg  *Message
+	str  string
+	done bool
+	ok   bool
+}
+
+// get returns the type tags of the message. It returns false if they can't be
+// computed, e.g. because of an unsupported argument.
+func (t *typeTags) get() (string, bool) {
+	if !t.done {
+		var err error
+		t.str, err = t.msg.TypeTags()
+		t.done, t.ok = true, err == nil
+	}
+	return t.str, t.ok
+}
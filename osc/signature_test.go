@@ -0,0 +1,175 @@
+package osc
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseRouteKey(t *testing.T) {
+	for _, tt := range []struct {
+		desc     string
+		s        string
+		addr     string
+		typeTags string
+		ok       bool
+	}{
+		{"address", "/fader", "/fader", "", true},
+		{"default", "*", "*", "", true},
+		{"type tags", "/fader ,f", "/fader", ",f", true},
+		{"no arguments", "/fader ,", "/fader", ",", true},
+		{"wildcards", "/fader ,i*", "/fader", ",i*", true},
+		{"array", "/fader ,[if]", "/fader", ",[if]", true},
+		{"array wildcards", "/fader ,[*]i", "/fader", ",[*]i", true},
+		{"array alternatives", "/fader ,{i,[ii]}", "/fader", ",{i,[ii]}", true},
+		{"no comma", "/fader f", "", "", false},
+		{"bad pattern", "/fader ,{f", "", "", false},
+		{"unbalanced array", "/fader ,[f", "", "", false},
+		{"unbalanced array end", "/fader ,f]", "", "", false},
+		{"unbalanced array alternative", "/fader ,{[f,i}]", "", "", false},
+		{"bad address", "/fader* ,f", "", "", false},
+		{"default with type tags", "* ,f", "", "", false},
+	} {
+		key, _, err := parseRouteKey(tt.s)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: parseRouteKey(%q) error = %v, want ok = %t", tt.desc, tt.s, err, tt.ok)
+			continue
+		}
+		if tt.ok && (key.addr != tt.addr || key.typeTags != tt.typeTags) {
+			t.Errorf("%s: parseRouteKey(%q) = %+v, want = {%s %s}", tt.desc, tt.s, key, tt.addr, tt.typeTags)
+		}
+	}
+
+	if _, _, err := parseRouteKey("/fader f"); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("parseRouteKey() error = %v, want = %v", err, ErrBadPattern)
+	}
+}
+
+func TestSignatureMatch(t *testing.T) {
+	for _, tt := range []struct {
+		pattern string
+		tags    string
+		want    bool
+	}{
+		{",if", ",if", true},
+		{",if", ",fi", false},
+		{",?", ",i", true},
+		{",?", ",[ii]", true},
+		{",??", ",[]", false},
+		{",??", ",[i]f", true},
+		{",*", ",", true},
+		{",*", ",i[f]s", true},
+		{",i*", ",f", false},
+		{",[ii]", ",[ii]", true},
+		{",[ii]", ",i", false},
+		{",[*]", ",[]", true},
+		{",[*]", ",[if[s]]", true},
+		{",[*]", ",[i][i]", false},
+		{",[*]", ",i", false},
+		{",[?]*", ",[i][i]", true},
+		{",*[s]", ",i[s]", true},
+		{",[[?]]", ",[[i]]", true},
+		{",[[?]]", ",[i]", false},
+		{",{i,[ii]}f", ",[ii]f", true},
+		{",{i,[ii]}f", ",if", true},
+		{",{i,[ii]}f", ",[i]f", false},
+	} {
+		sig, err := parseSignature(tt.pattern)
+		if err != nil {
+			t.Errorf("parseSignature(%q) unexpected error: %s", tt.pattern, err)
+			continue
+		}
+		if got := sig.match(tt.tags); got != tt.want {
+			t.Errorf("%q.match(%q) = %t, want = %t", tt.pattern, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestDispatcherTypeTagRouting(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var calls []string
+		handler := func(name string) HandlerFunc {
+			return func(msg *Message) { calls = append(calls, name) }
+		}
+		dispatch := func(msg *Message) string {
+			calls = nil
+			d.Dispatch(msg)
+			sort.Strings(calls)
+			return strings.Join(calls, "|")
+		}
+
+		for _, addr := range []string{"/fader ,f", "/fader ,?*", "/fader ,i*", "/fader", "/mute ,T"} {
+			if err := d.AddMsgHandler(addr, handler(addr)); err != nil {
+				t.Fatalf("%s: AddMsgHandler(%q) unexpected error: %s", td.name, addr, err)
+			}
+		}
+		if err := d.AddMsgHandler("/fader ,f", handler("")); err == nil {
+			t.Errorf("%s: AddMsgHandler() of an existing type tag pattern expected an error", td.name)
+		}
+
+		for _, tt := range []struct {
+			msg  *Message
+			want string
+		}{
+			{NewMessage("/fader", float32(0.5)), "/fader ,f"},
+			{NewMessage("/fader", int32(1), "x"), "/fader ,?*"},
+			{NewMessage("/fader"), "/fader"},
+			{NewMessage("/*", true), "/fader ,?*|/mute ,T"},
+			{NewMessage("/mute", false), ""},
+			// Messages with unsupported arguments only match the fallback.
+			{NewMessage("/fader", 1), "/fader"},
+		} {
+			if got := dispatch(tt.msg); got != tt.want {
+				t.Errorf("%s: Dispatch(%s) called %q, want = %q", td.name, tt.msg, got, tt.want)
+			}
+		}
+
+		// The type tag patterns are part of the address when removing.
+		if err := d.RemoveMsgHandler("/fader ,?*"); err != nil {
+			t.Errorf("%s: RemoveMsgHandler() unexpected error: %s", td.name, err)
+		}
+		if got, want := dispatch(NewMessage("/fader", int32(1), "x")), "/fader ,i*"; got != want {
+			t.Errorf("%s: Dispatch() called %q, want = %q", td.name, got, want)
+		}
+		d.RemoveMsgHandler("/fader")
+		if got, want := dispatch(NewMessage("/fader", "x")), ""; got != want {
+			t.Errorf("%s: Dispatch() called %q, want = %q", td.name, got, want)
+		}
+	}
+}
+
+func TestDispatcherArrayTypeTagRouting(t *testing.T) {
+	for _, td := range testDispatchers {
+		d := td.new()
+		var calls []string
+		handler := func(name string) HandlerFunc {
+			return func(msg *Message) { calls = append(calls, name) }
+		}
+
+		for _, addr := range []string{"/a ,[ii]", "/a ,[*]", "/a ,i"} {
+			if err := d.AddMsgHandler(addr, handler(addr)); err != nil {
+				t.Fatalf("%s: AddMsgHandler(%q) unexpected error: %s", td.name, addr, err)
+			}
+		}
+
+		for _, tt := range []struct {
+			msg  *Message
+			want string
+		}{
+			{NewMessage("/a", []interface{}{int32(1), int32(2)}), "/a ,[ii]"},
+			{NewMessage("/a", []interface{}{int32(1)}), "/a ,[*]"},
+			{NewMessage("/a", []interface{}{}), "/a ,[*]"},
+			{NewMessage("/a", int32(1)), "/a ,i"},
+			{NewMessage("/a", int32(1), int32(2)), ""},
+			{NewMessage("/a", []interface{}{int32(1)}, []interface{}{int32(2)}), ""},
+		} {
+			calls = nil
+			d.Dispatch(tt.msg)
+			if got := strings.Join(calls, "|"); got != tt.want {
+				t.Errorf("%s: Dispatch(%s) called %q, want = %q", td.name, tt.msg, got, tt.want)
+			}
+		}
+	}
+}
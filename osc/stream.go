@@ -0,0 +1,140 @@
+package osc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+////
+// Streaming de/encoding
+////
+
+// lightMarshaler is implemented by packets that can marshal themselves into
+// an existing buffer.
+type lightMarshaler interface {
+	LightMarshalBinary(data *bytes.Buffer) error
+}
+
+// marshalPacket appends the packet `p` to `data`. Messages of `maxSize` bytes
+// or more are rejected.
+func marshalPacket(p Packet, data *bytes.Buffer, maxSize int) error {
+	switch p := p.(type) {
+	case *Message:
+		return p.marshal(data, maxSize)
+	case *Bundle:
+		return p.marshal(data, maxSize)
+	}
+
+	b, err := p.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	data.Write(b)
+	return nil
+}
+
+// An Encoder writes framed OSC packets to an output stream.
+type Encoder struct {
+	w       io.Writer
+	framing Framing
+	maxSize int
+	buf     bytes.Buffer
+}
+
+// NewEncoder returns a new Encoder that writes to `w`. The packets are framed
+// with SizePrefixFraming, use SetFraming to change this.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, framing: SizePrefixFraming, maxSize: MaxStreamPacketSize}
+}
+
+// SetFraming sets the framing used for the following packets.
+func (e *Encoder) SetFraming(f Framing) {
+	e.framing = f
+}
+
+// SetMaxPacketSize sets the maximum size of a message in bytes. Larger
+// messages are rejected. The default is MaxStreamPacketSize.
+func (e *Encoder) SetMaxPacketSize(n int) {
+	e.maxSize = n
+}
+
+// Encode writes the OSC packet `p` as a single frame to the stream.
+func (e *Encoder) Encode(p Packet) error {
+	e.buf.Reset()
+	if err := marshalPacket(p, &e.buf, e.maxSize); err != nil {
+		return err
+	}
+
+	return e.framing.WriteFrame(e.w, e.buf.Bytes())
+}
+
+// A Decoder reads framed OSC packets from an input stream.
+type Decoder struct {
+	r       *bufio.Reader
+	framing Framing
+	maxSize int
+	limits  *Limits
+	// defaults are the limits that are enforced if limits is nil.
+	defaults Limits
+	buf      []byte
+}
+
+// NewDecoder returns a new Decoder that reads from `r`. The packets are
+// expected to be framed with SizePrefixFraming, use SetFraming to change
+// this.
+func NewDecoder(r io.Reader) *Decoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &Decoder{r: br, framing: SizePrefixFraming, maxSize: MaxStreamPacketSize}
+}
+
+// SetFraming sets the framing used for the following packets.
+func (d *Decoder) SetFraming(f Framing) {
+	d.framing = f
+}
+
+// SetMaxPacketSize sets the maximum size of a packet in bytes. Larger packets
+// are rejected with ErrFrameTooLarge. The default is MaxStreamPacketSize.
+func (d *Decoder) SetMaxPacketSize(n int) {
+	d.maxSize = n
+}
+
+// SetLimits sets the limits that are enforced when decoding packets. If
+// `limits` is nil, the DefaultLimits are enforced, except that strings and
+// blobs may be as large as the maximum packet size.
+func (d *Decoder) SetLimits(limits *Limits) {
+	d.limits = limits
+}
+
+// Decode reads the next OSC packet from the stream. It returns io.EOF when
+// the stream ends between two packets. If a frame isn't a valid OSC packet or
+// was skipped by the framing, a *PacketError is returned and the stream can be
+// read on.
+func (d *Decoder) Decode() (Packet, error) {
+	frame, err := d.framing.ReadFrame(d.r, d.buf[:0], d.maxSize)
+	d.buf = frame
+	var fe *FrameError
+	if errors.As(err, &fe) {
+		return nil, &PacketError{Err: err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	limits := d.limits
+	if limits == nil {
+		d.defaults = DefaultLimits
+		d.defaults.MaxStringLength = d.maxSize
+		d.defaults.MaxBlobLength = d.maxSize
+		limits = &d.defaults
+	}
+	p, err := ReadPacketWithLimits(frame, limits)
+	if err != nil {
+		return nil, &PacketError{Data: append([]byte(nil), frame...), Err: err}
+	}
+	return p, nil
+}
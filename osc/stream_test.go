@@ -0,0 +1,96 @@
+package osc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncoderDecoder(t *testing.T) {
+	bundle := NewBundle(time.Unix(1000, 0))
+	bundle.Append(NewMessage("/b/1", int32(1)))
+	bundle.Append(NewMessage("/b/2", "two", []byte{0xC0, 0xDB}))
+
+	packets := []Packet{
+		NewMessage("/a", int32(1), float32(2), "three"),
+		NewMessage("/blob", []byte{0xC0, 0xDB, 0xC0}),
+		bundle,
+	}
+
+	for _, tt := range []struct {
+		desc    string
+		framing Framing
+	}{
+		{"size_prefix", SizePrefixFraming},
+		{"slip", SLIPFraming},
+	} {
+		buf := new(bytes.Buffer)
+		enc := NewEncoder(buf)
+		enc.SetFraming(tt.framing)
+		for _, p := range packets {
+			if err := enc.Encode(p); err != nil {
+				t.Fatalf("%s: Encode() unexpected error: %s", tt.desc, err)
+			}
+		}
+
+		dec := NewDecoder(buf)
+		dec.SetFraming(tt.framing)
+		for i, want := range packets {
+			got, err := dec.Decode()
+			if err != nil {
+				t.Fatalf("%s: Decode() #%d unexpected error: %s", tt.desc, i, err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: Decode() #%d = %v, want = %v", tt.desc, i, got, want)
+			}
+		}
+		if _, err := dec.Decode(); !errors.Is(err, io.EOF) {
+			t.Errorf("%s: Decode() at end of stream error = %v, want = %v", tt.desc, err, io.EOF)
+		}
+	}
+}
+
+func TestDecoderMaxPacketSize(t *testing.T) {
+	buf := new(bytes.Buffer)
+	if err := NewEncoder(buf).Encode(NewMessage("/a", "a longer string argument")); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(buf)
+	dec.SetMaxPacketSize(16)
+	if _, err := dec.Decode(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Decode() error = %v, want = %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestDecoderRecovery(t *testing.T) {
+	buf := new(bytes.Buffer)
+	// A frame that was cut off by a new frame, and a frame with an invalid
+	// escape sequence.
+	buf.Write([]byte{slipEnd, '/', 'a', slipEnd})
+	buf.Write([]byte{slipEnd, '/', slipEsc, 'x', slipEnd})
+	enc := NewEncoder(buf)
+	enc.SetFraming(SLIPFraming)
+	if err := enc.Encode(NewMessage("/b", int32(1))); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := NewDecoder(buf)
+	dec.SetFraming(SLIPFraming)
+	for i := 0; i < 2; i++ {
+		var pe *PacketError
+		if _, err := dec.Decode(); !errors.As(err, &pe) {
+			t.Errorf("Decode() #%d error = %v, want a *PacketError", i, err)
+		}
+	}
+	p, err := dec.Decode()
+	if err != nil {
+		t.Fatalf("Decode() unexpected error: %s", err)
+	}
+	if want := NewMessage("/b", int32(1)); !reflect.DeepEqual(p, want) {
+		t.Errorf("Decode() = %v, want = %v", p, want)
+	}
+}
@@ -0,0 +1,172 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+////
+// Stream transport
+////
+
+// StreamConn sends and receives OSC packets on a stream, such as a TCP
+// connection. By default the packets are framed with SizePrefixFraming, as
+// specified by OSC 1.0 for stream transports. Unlike datagrams, the packets
+// may be larger than MaxPacketSize. Send is safe for concurrent use, Receive
+// and Serve must not be called concurrently.
+type StreamConn struct {
+	// Framing delimits the packets on the stream. If nil,
+	// SizePrefixFraming is used.
+	Framing Framing
+	// MaxPacketSize is the maximum size of the packets in bytes. If zero,
+	// MaxStreamPacketSize is used.
+	MaxPacketSize int
+	// ReadTimeout, Limits and ErrorHandler are used for receiving packets,
+	// see Server. The ReadTimeout only applies to streams that have a
+	// SetReadDeadline method, like a net.Conn. If Limits is nil, strings
+	// and blobs may be as large as the packets.
+	ReadTimeout  time.Duration
+	Limits       *Limits
+	ErrorHandler func(err error)
+
+	rw  io.ReadWriter
+	dec *Decoder
+	// mu serializes the packets that are sent.
+	mu  sync.Mutex
+	enc *Encoder
+}
+
+// NewStreamConn returns a StreamConn that sends and receives packets on
+// `rw`.
+func NewStreamConn(rw io.ReadWriter) *StreamConn {
+	return &StreamConn{rw: rw, dec: NewDecoder(rw), enc: NewEncoder(rw)}
+}
+
+// DialTCP connects to the OSC server at the TCP address `addr` and returns a
+// StreamConn for the connection. Use Receive or Serve to handle the packets
+// that the server sends back.
+func DialTCP(addr string) (*StreamConn, error) {
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewStreamConn(c), nil
+}
+
+// NewSLIPConn returns a StreamConn that sends and receives packets on `rw`
+// with SLIPFraming, as specified by OSC 1.1 for stream transports, e.g. on a
+// serial port.
+func NewSLIPConn(rw io.ReadWriter) *StreamConn {
+	c := NewStreamConn(rw)
+	c.Framing = SLIPFraming
+	return c
+}
+
+// DialSLIP is like DialTCP, but the packets are framed with SLIPFraming.
+func DialSLIP(addr string) (*StreamConn, error) {
+	c, err := DialTCP(addr)
+	if err != nil {
+		return nil, err
+	}
+	c.Framing = SLIPFraming
+	return c, nil
+}
+
+// framing returns the Framing of the StreamConn.
+func (c *StreamConn) framing() Framing {
+	if c.Framing == nil {
+		return SizePrefixFraming
+	}
+	return c.Framing
+}
+
+// maxPacketSize returns the maximum packet size of the StreamConn.
+func (c *StreamConn) maxPacketSize() int {
+	if c.MaxPacketSize <= 0 {
+		return MaxStreamPacketSize
+	}
+	return c.MaxPacketSize
+}
+
+// Send sends an OSC Bundle or an OSC Message as a single frame.
+func (c *StreamConn) Send(packet Packet) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enc.SetFraming(c.framing())
+	c.enc.SetMaxPacketSize(c.maxPacketSize())
+	return c.enc.Encode(packet)
+}
+
+// readDeadliner is implemented by streams that support read timeouts.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// Receive waits for the next OSC packet and returns it. It returns io.EOF if
+// the stream ends between two packets. A *PacketError is returned if a
+// packet can't be decoded, the following packets can still be received.
+func (c *StreamConn) Receive() (Packet, error) {
+	if rd, ok := c.rw.(readDeadliner); ok && c.ReadTimeout != 0 {
+		if err := rd.SetReadDeadline(time.Now().Add(c.ReadTimeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	c.dec.SetFraming(c.framing())
+	c.dec.SetMaxPacketSize(c.maxPacketSize())
+	c.dec.SetLimits(c.Limits)
+	p, err := c.dec.Decode()
+	var pe *PacketError
+	if errors.As(err, &pe) {
+		pe.Addr = c.RemoteAddr()
+	}
+	return p, err
+}
+
+// Serve retrieves incoming OSC packets and dispatches them with `d`, like
+// Server.Serve. The handlers can reply with Reply, which sends the replies
+// over the StreamConn. If `d` is nil, a StandardDispatcher without handlers
+// is used. Serve returns nil when the stream ends. It shouldn't be used
+// together with Receive.
+func (c *StreamConn) Serve(d Dispatcher) error {
+	if d == nil {
+		d = NewStandardDispatcher()
+	}
+	s := &Server{Dispatcher: d, ErrorHandler: c.ErrorHandler}
+	err := s.serveStream(withErrorReporter(context.Background(), s.reportError), c, nil)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// LocalAddr returns the local address of the stream if it is a net.Conn, or
+// nil.
+func (c *StreamConn) LocalAddr() net.Addr {
+	if nc, ok := c.rw.(net.Conn); ok {
+		return nc.LocalAddr()
+	}
+	return nil
+}
+
+// RemoteAddr returns the remote address of the stream if it is a net.Conn,
+// or nil.
+func (c *StreamConn) RemoteAddr() net.Addr {
+	if nc, ok := c.rw.(net.Conn); ok {
+		return nc.RemoteAddr()
+	}
+	return nil
+}
+
+// Close closes the stream if it is an io.Closer. A running Serve or Receive
+// returns an error.
+func (c *StreamConn) Close() error {
+	if closer, ok := c.rw.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
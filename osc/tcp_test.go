@@ -0,0 +1,252 @@
+package osc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// serveTCPTest serves TCP connections with `s` and returns the address of the
+// listener.
+func serveTCPTest(t *testing.T, s *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.ServeListener(l)
+	waitServing(s)
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+func TestServerTCP(t *testing.T) {
+	d := NewStandardDispatcher()
+	d.AddHandler("/echo", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+		if err := Reply(ctx, NewMessage("/reply", msg.Arguments...)); err != nil {
+			t.Errorf("Reply() unexpected error: %s", err)
+		}
+	}))
+	addr := serveTCPTest(t, &Server{Dispatcher: d})
+
+	// Two connections are served at the same time.
+	var clients []*StreamConn
+	for i := 0; i < 2; i++ {
+		c, err := DialTCP(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.ReadTimeout = 5 * time.Second
+		clients = append(clients, c)
+	}
+
+	// Packets aren't limited to MaxPacketSize on streams.
+	blob := bytes.Repeat([]byte{0xC0, 0xDB}, MaxPacketSize)
+	for i, c := range clients {
+		want := NewMessage("/reply", int32(i), blob)
+		if err := c.Send(NewMessage("/echo", int32(i), blob)); err != nil {
+			t.Fatalf("Send() unexpected error: %s", err)
+		}
+		got, err := c.Receive()
+		if err != nil {
+			t.Fatalf("Receive() unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Receive() = %.40v, want = %.40v", got, want)
+		}
+	}
+}
+
+func TestServerTCPBadPacket(t *testing.T) {
+	received := make(chan struct{}, 1)
+	d := NewStandardDispatcher()
+	d.AddMsgHandler("/a", func(msg *Message) { received <- struct{}{} })
+	deadLetters := make(chan *PacketError, 1)
+	addr := serveTCPTest(t, &Server{Dispatcher: d, DeadLetters: deadLetters})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if err = SizePrefixFraming.WriteFrame(conn, []byte("garbage!")); err != nil {
+		t.Fatal(err)
+	}
+	if err = NewStreamConn(conn).Send(NewMessage("/a")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case pe := <-deadLetters:
+		if string(pe.Data) != "garbage!" || pe.Addr.String() != conn.LocalAddr().String() {
+			t.Errorf("dead letter = %q from %v, want = %q from %v", pe.Data, pe.Addr, "garbage!", conn.LocalAddr())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the dead letter")
+	}
+	select {
+	case <-received:
+	case <-time.After(time.Second):
+		t.Fatal("the connection was closed after a bad packet")
+	}
+}
+
+func TestServerTCPShutdown(t *testing.T) {
+	s := &Server{Network: "tcp", Addr: "127.0.0.1:0", Dispatcher: NewStandardDispatcher()}
+	served := make(chan error, 1)
+	go func() { served <- s.ListenAndServe() }()
+	waitServing(s)
+
+	s.mu.Lock()
+	var addr string
+	for c := range s.conns {
+		addr = c.(net.Listener).Addr().String()
+	}
+	s.mu.Unlock()
+	c, err := DialTCP(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Send(NewMessage("/a")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() unexpected error: %s", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("ListenAndServe() error = %v, want = %v", err, ErrServerClosed)
+	}
+	c.ReadTimeout = time.Second
+	if _, err := c.Receive(); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Receive() error = %v, want the connection to be closed", err)
+	}
+}
+
+func TestStreamConnMaxPacketSize(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	sender, receiver := NewStreamConn(a), NewStreamConn(b)
+	sender.MaxPacketSize = 64
+
+	if err := sender.Send(NewMessage("/a", make([]byte, 64))); err == nil {
+		t.Error("Send() of a packet larger than MaxPacketSize expected an error")
+	}
+	if _, err := NewMessage("/a", make([]byte, MaxPacketSize)).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary() of a packet larger than MaxPacketSize expected an error")
+	}
+
+	receiver.MaxPacketSize = 16
+	go sender.Send(NewMessage("/a", "a longer string argument"))
+	if _, err := receiver.Receive(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("Receive() error = %v, want = %v", err, ErrFrameTooLarge)
+	}
+}
+
+func TestServerServeStream(t *testing.T) {
+	d := NewTrieDispatcher()
+	d.AddHandler("/echo", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+		Reply(ctx, NewMessage("/reply", msg.Arguments...))
+	}))
+	deadLetters := make(chan *PacketError, 1)
+	s := &Server{Dispatcher: d, Framing: SLIPFraming, DeadLetters: deadLetters}
+
+	a, b := net.Pipe()
+	defer a.Close()
+	served := make(chan error, 1)
+	go func() { served <- s.ServeStream(b) }()
+
+	// A partial frame followed by a valid one.
+	go func() {
+		a.Write([]byte{slipEnd, '/', 'e', 'c'})
+		NewSLIPConn(a).Send(NewMessage("/echo", "x"))
+	}()
+	select {
+	case <-deadLetters:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the dead letter")
+	}
+
+	c := NewSLIPConn(a)
+	got, err := c.Receive()
+	if err != nil {
+		t.Fatalf("Receive() unexpected error: %s", err)
+	}
+	if want := NewMessage("/reply", "x"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Receive() = %v, want = %v", got, want)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown() unexpected error: %s", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("ServeStream() error = %v, want = %v", err, ErrServerClosed)
+	}
+}
+
+func TestDialSLIP(t *testing.T) {
+	d := NewStandardDispatcher()
+	d.AddHandler("/echo", ContextHandlerFunc(func(ctx context.Context, msg *Message) {
+		Reply(ctx, NewMessage("/reply", msg.Arguments...))
+	}))
+	addr := serveTCPTest(t, &Server{Dispatcher: d, Framing: SLIPFraming})
+
+	c, err := DialSLIP(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.ReadTimeout = 5 * time.Second
+
+	// The arguments contain the SLIP END and ESC bytes.
+	want := NewMessage("/reply", []byte{slipEnd, slipEsc, slipEnd}, int32(0xC0DBC0))
+	if err := c.Send(NewMessage("/echo", want.Arguments...)); err != nil {
+		t.Fatalf("Send() unexpected error: %s", err)
+	}
+	got, err := c.Receive()
+	if err != nil {
+		t.Fatalf("Receive() unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Receive() = %v, want = %v", got, want)
+	}
+}
+
+func TestStreamConnServeNilDispatcher(t *testing.T) {
+	a, b := net.Pipe()
+	c := NewStreamConn(a)
+	errs := make(chan error, 1)
+	c.ErrorHandler = func(err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}
+	done := make(chan error)
+	go func() { done <- c.Serve(nil) }()
+
+	if err := NewStreamConn(b).Send(NewMessage("/a")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		t.Errorf("Serve(nil) reported %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	b.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v, want = nil", err)
+	}
+}
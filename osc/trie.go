@@ -0,0 +1,358 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TrieDispatcher is a dispatcher for OSC packets that stores the handlers in
+// a tree of address parts. Dispatching a message walks the tree along the
+// parts of its address pattern, so the time it takes depends on the depth of
+// the address rather than on the number of registered handlers. Handlers can
+// be added and removed while packets are being dispatched.
+type TrieDispatcher struct {
+	// mu serializes the changes of the handlers.
+	mu sync.Mutex
+	// root holds the current *trieRoot. The nodes are never modified, a
+	// change copies the nodes on the path to the changed node instead, so
+	// dispatching doesn't need to lock.
+	root     atomic.Value
+	patterns *patternCache
+	// scheduler dispatches the bundles at the time of their time tags.
+	scheduler *scheduler
+}
+
+// Verify that TrieDispatcher implements the ContextDispatcher interface.
+var _ ContextDispatcher = (*TrieDispatcher)(nil)
+
+// trieRoot is an immutable set of handlers of a TrieDispatcher.
+type trieRoot struct {
+	node         *trieNode
+	defaultRoute *route
+	middleware   []Middleware
+}
+
+// trieNode is a part of an OSC address in a TrieDispatcher.
+type trieNode struct {
+	children map[string]*trieNode
+	routes   *routeSet
+}
+
+// NewTrieDispatcher returns a TrieDispatcher.
+func NewTrieDispatcher() *TrieDispatcher {
+	t := &TrieDispatcher{patterns: newPatternCache(defaultPatternCacheSize)}
+	t.root.Store(&trieRoot{node: &trieNode{}})
+	t.scheduler = newScheduler(t)
+	return t
+}
+
+// AddMsgHandler adds a new message handler for the given OSC address. The
+// address "*" sets the default handler, which is called for every message.
+// The address may be followed by a space and a type tag pattern, e.g.
+// "/fader ,f", to only handle the messages whose type tags match it. A
+// handler without type tag pattern handles the messages that match none of
+// the patterns of the address. The handler is wrapped with `middleware`,
+// inside the middleware added with Use.
+func (t *TrieDispatcher) AddMsgHandler(addr string, handler HandlerFunc, middleware ...Middleware) error {
+	_, err := addRoute(t, addr, handler, middleware)
+	return err
+}
+
+// AddHandler adds a new handler for the given OSC address, like AddMsgHandler.
+// If the handler is a ContextHandler, it is called with the context of the
+// message.
+func (t *TrieDispatcher) AddHandler(addr string, handler Handler, middleware ...Middleware) error {
+	_, err := addRoute(t, addr, handler, middleware)
+	return err
+}
+
+// RemoveMsgHandler removes the message handler for the given OSC address.
+func (t *TrieDispatcher) RemoveMsgHandler(addr string) error {
+	return removeRoute(t, addr)
+}
+
+// ReplaceMsgHandler replaces the message handler for the given OSC address,
+// or adds it if there is none.
+func (t *TrieDispatcher) ReplaceMsgHandler(addr string, handler HandlerFunc, middleware ...Middleware) error {
+	return replaceRoute(t, addr, handler, middleware)
+}
+
+// Subscribe adds a new message handler for the given OSC address, like
+// AddMsgHandler, and returns a Subscription to remove it again.
+func (t *TrieDispatcher) Subscribe(addr string, handler HandlerFunc, middleware ...Middleware) (*Subscription, error) {
+	return addRoute(t, addr, handler, middleware)
+}
+
+// Use adds middleware that wraps all handlers, including the ones that were
+// added before and the default handler.
+func (t *TrieDispatcher) Use(middleware ...Middleware) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	root := t.root.Load().(*trieRoot)
+	mw := append(root.middleware[:len(root.middleware):len(root.middleware)], middleware...)
+	var defaultRoute *route
+	if root.defaultRoute != nil {
+		defaultRoute = root.defaultRoute.wrap(mw)
+	}
+	t.root.Store(&trieRoot{node: root.node.wrap(mw), defaultRoute: defaultRoute, middleware: mw})
+}
+
+// updateRoute implements the routeUpdater interface.
+func (t *TrieDispatcher) updateRoute(key routeKey, fn func(old *route) (*route, error)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	root := t.root.Load().(*trieRoot)
+	if key.addr == "*" {
+		r, err := fn(root.defaultRoute)
+		if err != nil || r == root.defaultRoute {
+			return err
+		}
+		if r != nil {
+			r = r.wrap(root.middleware)
+		}
+		t.root.Store(&trieRoot{node: root.node, defaultRoute: r, middleware: root.middleware})
+		return nil
+	}
+
+	if !strings.HasPrefix(key.addr, "/") {
+		return errors.New("OSC address must start with '/'")
+	}
+	parts := strings.Split(key.addr[1:], "/")
+	n := root.node
+	for _, part := range parts {
+		if n = n.children[part]; n == nil {
+			break
+		}
+	}
+
+	old := (*route)(nil)
+	if n != nil {
+		old = n.routes.get(key.typeTags)
+	}
+	r, err := fn(old)
+	if err != nil || r == old {
+		return err
+	}
+
+	if r != nil {
+		r = r.wrap(root.middleware)
+	}
+	node := root.node.with(parts, key.typeTags, r)
+	if node == nil {
+		node = &trieNode{}
+	}
+	t.root.Store(&trieRoot{node: node, defaultRoute: root.defaultRoute, middleware: root.middleware})
+	return nil
+}
+
+// with returns a copy of `n` in which the node at the path `parts` has the
+// route `r` for the type tag pattern `typeTags`. Nodes without routes and
+// children are removed, in which case nil is returned for `n` itself.
+func (n *trieNode) with(parts []string, typeTags string, r *route) *trieNode {
+	c := &trieNode{}
+	if n != nil {
+		*c = *n
+	}
+
+	if len(parts) == 0 {
+		c.routes = c.routes.with(typeTags, r)
+	} else {
+		children := make(map[string]*trieNode, len(c.children)+1)
+		for name, child := range c.children {
+			children[name] = child
+		}
+		if child := c.children[parts[0]].with(parts[1:], typeTags, r); child != nil {
+			children[parts[0]] = child
+		} else {
+			delete(children, parts[0])
+		}
+		c.children = children
+	}
+
+	if c.routes == nil && len(c.children) == 0 {
+		return nil
+	}
+	return c
+}
+
+// wrap returns a copy of `n` and its descendants in which the handlers are
+// wrapped with the dispatcher middleware `middleware`.
+func (n *trieNode) wrap(middleware []Middleware) *trieNode {
+	c := &trieNode{}
+	if n.routes != nil {
+		c.routes = n.routes.wrap(middleware)
+	}
+	if n.children != nil {
+		c.children = make(map[string]*trieNode, len(n.children))
+		for name, child := range n.children {
+			c.children[name] = child.wrap(middleware)
+		}
+	}
+	return c
+}
+
+// Dispatch dispatches OSC packets. Implements the Dispatcher interface.
+func (t *TrieDispatcher) Dispatch(packet Packet) {
+	t.DispatchContext(context.Background(), packet)
+}
+
+// DispatchContext dispatches OSC packets. If `ctx` comes from a Server, the
+// panics of the handlers are recovered and reported to its ErrorHandler.
+// Implements the ContextDispatcher interface.
+func (t *TrieDispatcher) DispatchContext(ctx context.Context, packet Packet) {
+	switch p := packet.(type) {
+	default:
+		return
+
+	case *Message:
+		t.dispatchMessage(ctx, p)
+
+	case *Bundle:
+		t.scheduler.schedule(ctx, p)
+	}
+}
+
+// Stop stops dispatching bundles. The pending bundles are discarded, and the
+// bundles dispatched afterwards are dropped. Messages are still dispatched.
+func (t *TrieDispatcher) Stop() {
+	t.scheduler.stop()
+}
+
+// PendingBundles returns the number of bundles that wait for their time tag
+// or whose elements are being dispatched.
+func (t *TrieDispatcher) PendingBundles() int {
+	return t.scheduler.pending()
+}
+
+// SetLatePolicy sets what happens with the bundles that are dispatched more
+// than `tolerance` after their time tag. By default they are dispatched right
+// away.
+func (t *TrieDispatcher) SetLatePolicy(policy LatePolicy, tolerance time.Duration) {
+	t.scheduler.setLatePolicy(policy, tolerance)
+}
+
+// dispatchMessage calls the handlers whose address matches the address
+// pattern of `msg`, followed by the default handler.
+func (t *TrieDispatcher) dispatchMessage(ctx context.Context, msg *Message) {
+	root := t.root.Load().(*trieRoot)
+	if pattern, err := t.patterns.get(msg.Address); err == nil {
+		w := trieWalker{ctx: ctx, parts: pattern.parts, msg: msg, tags: typeTags{msg: msg}}
+		w.walk(root.node, 0)
+	}
+	if root.defaultRoute != nil {
+		callHandler(ctx, root.defaultRoute.handler, msg)
+	}
+}
+
+// trieWalker calls the handlers of the nodes that match the address pattern
+// of a message.
+type trieWalker struct {
+	ctx   context.Context
+	parts []patternPart
+	msg   *Message
+	tags  typeTags
+	// visited holds the nodes that were visited for each pattern part. It is
+	// only used once a '//' was reached, since only then a node can be
+	// reached in more than one way.
+	visited map[trieVisit]bool
+}
+
+// trieVisit is a node that was visited for the pattern part at index `part`.
+type trieVisit struct {
+	node *trieNode
+	part int
+}
+
+// walk calls the handlers below `n` that match the pattern parts from
+// index `p` on.
+func (w *trieWalker) walk(n *trieNode, p int) {
+	// The match-all pattern "*" has no parts.
+	if w.parts == nil {
+		w.walkAll(n)
+		return
+	}
+
+	part := (*patternPart)(nil)
+	if p < len(w.parts) {
+		part = &w.parts[p]
+		if part.descendant && w.visited == nil {
+			w.visited = make(map[trieVisit]bool)
+		}
+	}
+	if w.visited != nil {
+		if w.visited[trieVisit{n, p}] {
+			return
+		}
+		w.visited[trieVisit{n, p}] = true
+	}
+
+	if part == nil {
+		w.handle(n)
+		return
+	}
+
+	switch {
+	case part.descendant:
+		w.walk(n, p+1)
+		for _, child := range n.children {
+			w.walk(child, p)
+		}
+
+	case len(part.tokens) == 1 && part.tokens[0].kind == tokenLiteral:
+		if child, ok := n.children[part.tokens[0].lit]; ok {
+			w.walk(child, p+1)
+		}
+
+	case len(part.tokens) == 1 && part.tokens[0].kind == tokenAlt:
+		alts := part.tokens[0].alts
+		for i, alt := range alts {
+			if containsString(alts[:i], alt) {
+				continue
+			}
+			if child, ok := n.children[alt]; ok {
+				w.walk(child, p+1)
+			}
+		}
+
+	default:
+		for name, child := range n.children {
+			if part.match(name) {
+				w.walk(child, p+1)
+			}
+		}
+	}
+}
+
+// walkAll calls the handlers of `n` and all its descendants.
+func (w *trieWalker) walkAll(n *trieNode) {
+	w.handle(n)
+	for _, child := range n.children {
+		w.walkAll(child)
+	}
+}
+
+// handle calls the handler of `n` that matches the type tags of the message.
+func (w *trieWalker) handle(n *trieNode) {
+	if n.routes == nil {
+		return
+	}
+	if r := n.routes.match(&w.tags); r != nil {
+		callHandler(w.ctx, r.handler, w.msg)
+	}
+}
+
+// containsString reports whether `s` is in `list`.
+func containsString(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
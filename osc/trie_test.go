@@ -0,0 +1,119 @@
+package osc
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+func TestTrieDispatcherAddMsgHandler(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		addr string
+		ok   bool
+	}{
+		{"valid", "/address/test", true},
+		{"root", "/", true},
+		{"wildcard", "/address*/test", false},
+		{"space", "/address test", false},
+		{"no slash", "address/test", false},
+		{"duplicate", "/a/b", false},
+	} {
+		d := NewTrieDispatcher()
+		if err := d.AddMsgHandler("/a/b", func(msg *Message) {}); err != nil {
+			t.Fatal(err)
+		}
+		err := d.AddMsgHandler(tt.addr, func(msg *Message) {})
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: AddMsgHandler(%q) error = %v, want ok = %t", tt.desc, tt.addr, err, tt.ok)
+		}
+	}
+}
+
+func TestTrieDispatcherDispatch(t *testing.T) {
+	addrs := []string{
+		"/",
+		"/a",
+		"/a/b",
+		"/a/b/c",
+		"/a/c",
+		"/a/b/a/b",
+		"/mixer/1/fader",
+		"/mixer/2/fader",
+		"/mixer/10/fader",
+		"/mixer/1/mute",
+		"/x/",
+		"/x//y",
+	}
+
+	// The trie dispatcher must call the same handlers as the standard one.
+	dispatched := func(d testDispatcher, pattern string) []string {
+		var got []string
+		for _, addr := range addrs {
+			addr := addr
+			if err := d.AddMsgHandler(addr, func(msg *Message) { got = append(got, addr) }); err != nil {
+				t.Fatalf("AddMsgHandler(%q) unexpected error: %s", addr, err)
+			}
+		}
+		d.Dispatch(NewMessage(pattern))
+		sort.Strings(got)
+		return got
+	}
+
+	for _, pattern := range []string{
+		"*",
+		"/",
+		"/a",
+		"/a/b",
+		"/a/?",
+		"/a/*",
+		"/a/[!b]",
+		"/a/{b,c}/c",
+		"/a/{b,b,c}",
+		"/mixer/*/fader",
+		"/mixer/?/fader",
+		"/mixer/{1,10}/*",
+		"//b",
+		"//a//b",
+		"/a//c",
+		"//fader",
+		"//",
+		"/x/",
+		"/x//y",
+		"/nope",
+		"/a[",
+	} {
+		got := dispatched(NewTrieDispatcher(), pattern)
+		want := dispatched(NewStandardDispatcher(), pattern)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("Dispatch(%q) called %v, want = %v", pattern, got, want)
+		}
+	}
+}
+
+func TestTrieDispatcherDefaultHandler(t *testing.T) {
+	d := NewTrieDispatcher()
+	var calls []string
+	d.AddMsgHandler("/a", func(msg *Message) { calls = append(calls, "a") })
+	d.AddMsgHandler("*", func(msg *Message) { calls = append(calls, "*") })
+
+	d.Dispatch(NewMessage("/a"))
+	d.Dispatch(NewMessage("/b"))
+	if got, want := fmt.Sprint(calls), "[a * *]"; got != want {
+		t.Errorf("handler calls = %s, want = %s", got, want)
+	}
+}
+
+func BenchmarkTrieDispatch(b *testing.B) {
+	d := NewTrieDispatcher()
+	for i := 0; i < 100; i++ {
+		d.AddMsgHandler(fmt.Sprintf("/mixer/%d/fader", i), func(msg *Message) {})
+	}
+	msg := NewMessage("/mixer/{1,2,3}/fader", float32(0.5))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		d.Dispatch(msg)
+	}
+}
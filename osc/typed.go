@@ -0,0 +1,149 @@
+package osc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+////
+// Typed handlers
+////
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// TypedHandler is a Handler that calls a Go function with the arguments of
+// the message, e.g.
+//
+//	func(ch int32, level float32)
+//
+// The parameters of the function are mapped to the arguments in order, and
+// the arguments are converted as described for Message.Scan. The function may
+// take a context.Context as first parameter, which is the context of the
+// message. It may be variadic, in which case the last parameter takes all
+// remaining arguments, and it may return an error.
+type TypedHandler struct {
+	// ErrorHandler is called if the arguments of a message don't match the
+	// parameters of the function, and with the error returned by the
+	// function. If nil, the errors are ignored.
+	ErrorHandler func(msg *Message, err error)
+
+	fn reflect.Value
+	// params are the parameters of the function without the context.
+	params []reflect.Type
+	// withContext is set if the function takes a context.Context.
+	withContext bool
+	// withError is set if the function returns an error.
+	withError bool
+}
+
+// Verify that TypedHandler implements the ContextHandler interface.
+var _ ContextHandler = (*TypedHandler)(nil)
+
+// NewTypedHandler returns a TypedHandler for the function `fn`. An error is
+// returned if `fn` isn't a function, if one of its parameters isn't a type
+// that GetTypeTag understands or interface{}, or if it returns something
+// other than an error.
+func NewTypedHandler(fn interface{}) (*TypedHandler, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("NewTypedHandler: not a function: %T", fn)
+	}
+	t := v.Type()
+
+	h := &TypedHandler{fn: v}
+	for i := 0; i < t.NumIn(); i++ {
+		p := t.In(i)
+		if i == 0 && p == contextType {
+			h.withContext = true
+			continue
+		}
+		arg := p
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			arg = p.Elem()
+		}
+		if !isArgType(arg) {
+			return nil, fmt.Errorf("NewTypedHandler: unsupported parameter type: %s", p)
+		}
+		h.params = append(h.params, p)
+	}
+
+	switch {
+	case t.NumOut() == 1 && t.Out(0) == errorType:
+		h.withError = true
+	case t.NumOut() != 0:
+		return nil, fmt.Errorf("NewTypedHandler: function may only return an error: %s", t)
+	}
+
+	return h, nil
+}
+
+// MustTypedHandler is like NewTypedHandler but panics if the function isn't
+// supported.
+func MustTypedHandler(fn interface{}) *TypedHandler {
+	h, err := NewTypedHandler(fn)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// HandleMessage calls the function with the arguments of `msg` and a
+// background context. Implements the Handler interface.
+func (h *TypedHandler) HandleMessage(msg *Message) {
+	h.HandleMessageContext(context.Background(), msg)
+}
+
+// HandleMessageContext calls the function with `ctx` and the arguments of
+// `msg`. Implements the ContextHandler interface.
+func (h *TypedHandler) HandleMessageContext(ctx context.Context, msg *Message) {
+	in, err := h.arguments(ctx, msg)
+	if err == nil {
+		out := h.fn.Call(in)
+		if h.withError && !out[0].IsNil() {
+			err = out[0].Interface().(error)
+		}
+	}
+	if err != nil && h.ErrorHandler != nil {
+		h.ErrorHandler(msg, err)
+	}
+}
+
+// arguments converts the arguments of `msg` to the parameters of the
+// function.
+func (h *TypedHandler) arguments(ctx context.Context, msg *Message) ([]reflect.Value, error) {
+	args := msg.Arguments
+	n := len(h.params)
+	variadic := h.fn.Type().IsVariadic()
+	if variadic {
+		n--
+	}
+	if variadic && len(args) < n {
+		return nil, fmt.Errorf("HandleMessage: got %d arguments, want at least %d", len(args), n)
+	}
+	if !variadic && len(args) != n {
+		return nil, fmt.Errorf("HandleMessage: got %d arguments, want %d", len(args), n)
+	}
+
+	in := make([]reflect.Value, 0, len(args)+1)
+	if h.withContext {
+		in = append(in, reflect.ValueOf(&ctx).Elem())
+	}
+	for i, arg := range args {
+		var t reflect.Type
+		if i < n {
+			t = h.params[i]
+		} else {
+			t = h.params[n].Elem()
+		}
+		v := reflect.New(t).Elem()
+		if err := setArg(v, arg); err != nil {
+			return nil, fmt.Errorf("HandleMessage: argument %d: %w", i, err)
+		}
+		in = append(in, v)
+	}
+	return in, nil
+}
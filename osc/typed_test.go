@@ -0,0 +1,136 @@
+package osc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewTypedHandler(t *testing.T) {
+	for _, tt := range []struct {
+		desc string
+		fn   interface{}
+		ok   bool
+	}{
+		{"no parameters", func() {}, true},
+		{"arguments", func(ch int32, level float32) {}, true},
+		{"context", func(ctx context.Context, s string) {}, true},
+		{"variadic", func(s string, rest ...interface{}) {}, true},
+		{"error result", func(b bool) error { return nil }, true},
+		{"not a function", 42, false},
+		{"nil function", (func())(nil), false},
+		{"unsupported parameter", func(i int) {}, false},
+		{"context not first", func(s string, ctx context.Context) {}, false},
+		{"unsupported variadic", func(i ...int) {}, false},
+		{"unsupported result", func() int { return 0 }, false},
+	} {
+		_, err := NewTypedHandler(tt.fn)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: NewTypedHandler() error = %v, want ok = %t", tt.desc, err, tt.ok)
+		}
+	}
+}
+
+func TestTypedHandler(t *testing.T) {
+	var got string
+	var errs []error
+	onError := func(msg *Message, err error) { errs = append(errs, err) }
+
+	for _, tt := range []struct {
+		desc string
+		fn   interface{}
+		msg  *Message
+		want string
+		ok   bool
+	}{
+		{
+			"arguments",
+			func(ch int32, level float32) { got = fmt.Sprint(ch, level) },
+			NewMessage("/a", int32(1), float32(0.5)),
+			"1 0.5", true,
+		},
+		{
+			"widening",
+			func(i int64, f float64, s string) { got = fmt.Sprintf("%d %g %s", i, f, s) },
+			NewMessage("/a", int32(1), float32(0.5), Symbol("x")),
+			"1 0.5 x", true,
+		},
+		{
+			"interface",
+			func(v interface{}) { got = fmt.Sprint(v) },
+			NewMessage("/a", nil),
+			"<nil>", true,
+		},
+		{
+			"variadic",
+			func(s string, rest ...int32) { got = fmt.Sprint(s, rest) },
+			NewMessage("/a", "x", int32(1), int32(2)),
+			"x[1 2]", true,
+		},
+		{
+			"variadic without rest",
+			func(s string, rest ...int32) { got = fmt.Sprint(s, rest) },
+			NewMessage("/a", "x"),
+			"x[]", true,
+		},
+		{
+			"wrong type",
+			func(ch int32) { got = "called" },
+			NewMessage("/a", "x"),
+			"", false,
+		},
+		{
+			"too few arguments",
+			func(ch int32, level float32) { got = "called" },
+			NewMessage("/a", int32(1)),
+			"", false,
+		},
+		{
+			"too many arguments",
+			func(ch int32) { got = "called" },
+			NewMessage("/a", int32(1), int32(2)),
+			"", false,
+		},
+		{
+			"error result",
+			func() error { got = "called"; return errors.New("failed") },
+			NewMessage("/a"),
+			"called", false,
+		},
+	} {
+		got, errs = "", nil
+		h := MustTypedHandler(tt.fn)
+		h.ErrorHandler = onError
+		h.HandleMessage(tt.msg)
+		if got != tt.want {
+			t.Errorf("%s: function called with %q, want = %q", tt.desc, got, tt.want)
+		}
+		if (len(errs) == 0) != tt.ok {
+			t.Errorf("%s: errors = %v, want ok = %t", tt.desc, errs, tt.ok)
+		}
+	}
+}
+
+func TestTypedHandlerContext(t *testing.T) {
+	type key struct{}
+	var got interface{}
+	d := NewTrieDispatcher()
+	d.AddHandler("/a", MustTypedHandler(func(ctx context.Context, s string) {
+		got = ctx.Value(key{})
+	}))
+
+	d.DispatchContext(context.WithValue(context.Background(), key{}, "value"), NewMessage("/a", "x"))
+	if got != "value" {
+		t.Errorf("context value = %v, want = value", got)
+	}
+}
+
+func TestMustTypedHandler(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MustTypedHandler() expected a panic")
+		}
+	}()
+	MustTypedHandler(func(i int) {})
+}
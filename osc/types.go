@@ -0,0 +1,49 @@
+package osc
+
+import "fmt"
+
+////
+// Extended OSC argument types
+////
+
+// Char represents an OSC 'c' argument, a 32-bit ASCII character.
+type Char rune
+
+// String implements the fmt.Stringer interface.
+func (c Char) String() string {
+	return string(rune(c))
+}
+
+// Symbol represents an OSC 'S' argument. It is encoded like a string, but
+// some implementations (e.g. SuperCollider) treat it as a distinct type.
+type Symbol string
+
+// RGBA represents an OSC 'r' argument, a 32-bit RGBA color.
+type RGBA struct {
+	R, G, B, A uint8
+}
+
+// String implements the fmt.Stringer interface.
+func (c RGBA) String() string {
+	return fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+}
+
+// MIDI represents an OSC 'm' argument, a 4 byte MIDI message. The bytes from
+// MSB to LSB are: port id, status byte, data1 and data2.
+type MIDI struct {
+	Port, Status, Data1, Data2 uint8
+}
+
+// String implements the fmt.Stringer interface.
+func (m MIDI) String() string {
+	return fmt.Sprintf("%02x%02x%02x%02x", m.Port, m.Status, m.Data1, m.Data2)
+}
+
+// Impulse represents an OSC 'I' argument (also known as Infinitum or Bang).
+// No bytes are allocated in the argument data.
+type Impulse struct{}
+
+// String implements the fmt.Stringer interface.
+func (Impulse) String() string {
+	return "Impulse"
+}
@@ -2,8 +2,8 @@ package osc
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	"sync"
 )
@@ -19,35 +19,13 @@ var (
 	}
 )
 
-// addressExists returns true if the OSC address `addr` is found in `handlers`.
-func addressExists(addr string, handlers map[string]Handler) bool {
-	for h := range handlers {
-		if h == addr {
-			return true
-		}
-	}
-	return false
-}
-
-// getRegEx compiles and returns a regular expression object for the given
-// address `pattern`.
-func getRegEx(pattern string) (*regexp.Regexp, error) {
-	for _, trs := range []struct {
-		old, new string
-	}{
-		{".", `\.`}, // Escape all '.' in the pattern
-		{"(", `\(`}, // Escape all '(' in the pattern
-		{")", `\)`}, // Escape all ')' in the pattern
-		{"*", ".*"}, // Replace a '*' with '.*' that matches zero or more chars
-		{"{", "("},  // Change a '{' to '('
-		{",", "|"},  // Change a ',' to '|'
-		{"}", ")"},  // Change a '}' to ')'
-		{"?", "."},  // Change a '?' to '.'
-	} {
-		pattern = strings.ReplaceAll(pattern, trs.old, trs.new)
+// validateAddress returns an error if `addr` contains characters that are
+// reserved for OSC address patterns.
+func validateAddress(addr string) error {
+	if strings.ContainsAny(addr, "*?,[]{}# ") {
+		return errors.New("OSC Address string may not contain any characters in \"*?,[]{}#")
 	}
-
-	return regexp.Compile(pattern)
+	return nil
 }
 
 // GetTypeTag returns the OSC type tag for the given argument.
@@ -74,6 +52,26 @@ func GetTypeTag(arg interface{}) (string, error) {
 		return "d", nil
 	case Timetag:
 		return "t", nil
+	case Char:
+		return "c", nil
+	case RGBA:
+		return "r", nil
+	case MIDI:
+		return "m", nil
+	case Symbol:
+		return "S", nil
+	case Impulse:
+		return "I", nil
+	case []interface{}:
+		tags := "["
+		for _, a := range t {
+			s, err := GetTypeTag(a)
+			if err != nil {
+				return "", err
+			}
+			tags += s
+		}
+		return tags + "]", nil
 	default:
 		return "", fmt.Errorf("unsupported type: %T", t)
 	}